@@ -1,25 +1,40 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
-	"strconv"
+	"os"
+	"time"
 
+	"github.com/Durelius/next-week/internal/api"
 	"github.com/Durelius/next-week/internal/graph"
-	"github.com/gorilla/mux"
+	"github.com/Durelius/next-week/internal/gtfs"
 )
 
 func main() {
-	slGraph, err := graph.NewWithData()
+	source := flag.String("gtfs", gtfsSourceFromEnv(), "GTFS feed directory or .zip archive, defaults to $GTFS_SOURCE")
+	snapshot := flag.String("snapshot", os.Getenv("GRAPH_SNAPSHOT"), "graph snapshot file for faster starts, defaults to $GRAPH_SNAPSHOT, empty to always build from the feed")
+	validate := flag.Bool("validate", false, "check the GTFS feed for errors and exit")
+	watch := flag.Duration("watch", 0, "how often to check the GTFS feed for changes and reload the graph, 0 to never")
+	flag.Parse()
+	if *validate {
+		os.Exit(validateFeed(*source))
+	}
+	slGraph, err := graph.Build(*source, *snapshot)
 	if err != nil {
 		log.Fatal(err)
 	}
-	r := mux.NewRouter()
-	r.HandleFunc("/stopbyname/{name}", GetStopsByNameEndpoint).Methods("GET")
-	r.HandleFunc("/path/{from}/{to}/{time}", GetPathEndpoint).Methods("GET")
+	server := api.NewServer(slGraph)
+	server.Source, server.SnapshotPath = *source, *snapshot
+	server.AdminToken = os.Getenv("ADMIN_TOKEN")
+	if *watch > 0 {
+		go server.Watch(context.Background(), *watch)
+	}
 	log.Println("Starting server at port 8080")
-	http.ListenAndServe(":8080", corsMiddleware(r))
+	http.ListenAndServe(":8080", server.Handler())
 	log.Println("test")
 	filteredVertices := slGraph.FindStopsByName("upplands väsby station")
 
@@ -28,62 +43,41 @@ func main() {
 	filteredVertices = slGraph.FindStopsByName("solna station")
 	chosenDestination := filteredVertices[0]
 
-	path := slGraph.FindRoute(chosenStartPoint, chosenDestination, 500)
+	path := slGraph.FindRoute(chosenStartPoint, chosenDestination, time.Now(), graph.DefaultProfile())
 	for _, edge := range path {
-		log.Printf("TripID: %s, From: %s, To: %s, Start: %d, Arrival: %d", edge.Metadata.TripID, edge.Source(), edge.Destination(), edge.Metadata.Departure, edge.Metadata.Arrival)
+		log.Printf("TripID: %s, From: %s, To: %s, Start: %s, Arrival: %s", edge.Metadata.TripID, edge.Metadata.SourceStopName, edge.Metadata.DestStopName, edge.DepartureTime, edge.ArrivalTime)
 	}
 
 }
-func GetStopsByNameEndpoint(w http.ResponseWriter, r *http.Request) {
-	name := mux.Vars(r)["name"]
-
-	nodes := graph.Instance().FindStopsByName(name)
-	stops := []graph.Stop{}
-	for _, v := range nodes {
-		stops = append(stops, *v.Metadata())
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
 
-	json.NewEncoder(w).Encode(stops)
-}
-func GetPathEndpoint(w http.ResponseWriter, r *http.Request) {
-	fromStopID := mux.Vars(r)["from"]
-	toStopID := mux.Vars(r)["to"]
-	startTimeStr := mux.Vars(r)["time"]
-	startTimeHours, err := strconv.Atoi(startTimeStr[0:2])
+// validateFeed prints the problems of a feed, returning the exit code
+func validateFeed(source string) int {
+	feed, err := gtfs.Load(source)
 	if err != nil {
 		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
-		return
+		return 1
 	}
-	startTimeMinutes, err := strconv.Atoi(startTimeStr[3:5])
+	defer feed.Close()
+	schedule, err := feed.ReadSchedule()
 	if err != nil {
 		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
-		return
+		return 1
 	}
-	minutesSinceMidnight := startTimeHours * 60
-	minutesSinceMidnight += startTimeMinutes
-	from := graph.Instance().GetVertexByID(fromStopID)
-	to := graph.Instance().GetVertexByID(toStopID)
-	path := graph.Instance().FindRoute(from, to, minutesSinceMidnight)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	json.NewEncoder(w).Encode(path)
+	problems := schedule.Validate()
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	log.Printf("%d problems found in %s", len(problems), source)
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
 }
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+
+// gtfsSourceFromEnv returns $GTFS_SOURCE, or the default feed location when unset
+func gtfsSourceFromEnv() string {
+	if source := os.Getenv("GTFS_SOURCE"); source != "" {
+		return source
+	}
+	return graph.DEFAULT_SOURCE
 }
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// error codes of the API, clients should switch on these and not the messages
+const (
+	CODE_INVALID_PARAMETER = "invalid_parameter"
+	CODE_BAD_TIME          = "bad_time"
+	CODE_UNKNOWN_STOP      = "unknown_stop"
+	CODE_NO_ROUTE          = "no_route"
+	CODE_UNAUTHORIZED      = "unauthorized"
+	CODE_INTERNAL          = "internal"
+)
+
+// Error is why a request failed, sent as {"error": {...}}
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	status  int
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// ErrorResponse is the body of every failed request
+type ErrorResponse struct {
+	Error *Error `json:"error"`
+}
+
+func newError(status int, code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), status: status}
+}
+
+func invalidParameter(name, value string) *Error {
+	return newError(http.StatusBadRequest, CODE_INVALID_PARAMETER, "invalid %s %q", name, value)
+}
+
+func badTime(format string, args ...any) *Error {
+	return newError(http.StatusBadRequest, CODE_BAD_TIME, format, args...)
+}
+
+func unknownStop(stopID string) *Error {
+	return newError(http.StatusNotFound, CODE_UNKNOWN_STOP, "unknown stop %q", stopID)
+}
+
+func noRoute() *Error {
+	return newError(http.StatusNotFound, CODE_NO_ROUTE, "no route found")
+}
+
+// writeJSON sends v with the status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends err as an error object, errors that aren't an *Error are
+// logged and sent as internal errors
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		log.Println(err)
+		apiErr = newError(http.StatusInternalServerError, CODE_INTERNAL, "internal error")
+	}
+	writeJSON(w, apiErr.status, ErrorResponse{Error: apiErr})
+}
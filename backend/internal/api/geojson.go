@@ -0,0 +1,90 @@
+package api
+
+import (
+	"time"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// defaultIsochroneBands are the upper bounds in minutes of the bands of the
+// isochrone when none are asked for, the last one is how far the search goes.
+// Every call returns a new slice the caller may change.
+func defaultIsochroneBands() []int {
+	return []int{15, 30, 45}
+}
+
+// limits of the bands asked for, the search time grows with the last one
+const (
+	MAX_ISOCHRONE_BANDS   = 8
+	MAX_ISOCHRONE_MINUTES = 120
+)
+
+// FeatureCollection is a GeoJSON feature collection of isochrone bands
+type FeatureCollection struct {
+	Type     string     `json:"type"` // always FeatureCollection
+	Features []*Feature `json:"features"`
+}
+
+// Feature is the stops of one band as a GeoJSON feature
+type Feature struct {
+	Type       string     `json:"type"` // always Feature
+	Geometry   MultiPoint `json:"geometry"`
+	Properties Band       `json:"properties"`
+}
+
+// MultiPoint is a GeoJSON geometry of points, each [longitude, latitude]
+type MultiPoint struct {
+	Type        string       `json:"type"` // always MultiPoint
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+// Band is the stops reached after more than FromMinutes and at most Minutes
+type Band struct {
+	FromMinutes int        `json:"fromMinutes"`
+	Minutes     int        `json:"minutes"`
+	Stops       []BandStop `json:"stops"` // in the order of the coordinates
+}
+
+// BandStop is a stop of a band and when it's reached
+type BandStop struct {
+	StopID   string    `json:"stopId"`
+	StopName string    `json:"stopName"`
+	Arrival  time.Time `json:"arrival"`
+	Seconds  int       `json:"seconds"` // since the departure
+}
+
+// isochroneBands groups the reached stops into a feature per band, stops
+// without coordinates can't be drawn and are left out
+func isochroneBands(reached []graph.Reach, bands []int) *FeatureCollection {
+	collection := &FeatureCollection{Type: "FeatureCollection", Features: []*Feature{}}
+	from := 0
+	for _, minutes := range bands {
+		collection.Features = append(collection.Features, &Feature{
+			Type:       "Feature",
+			Geometry:   MultiPoint{Type: "MultiPoint", Coordinates: [][2]float64{}},
+			Properties: Band{FromMinutes: from, Minutes: minutes, Stops: []BandStop{}},
+		})
+		from = minutes
+	}
+	band := 0
+	for _, r := range reached {
+		for band < len(bands) && r.Seconds > bands[band]*60 {
+			band++
+		}
+		if band == len(bands) {
+			break
+		}
+		if !r.Stop.HasCoordinates() {
+			continue
+		}
+		feature := collection.Features[band]
+		feature.Geometry.Coordinates = append(feature.Geometry.Coordinates, [2]float64{r.Stop.Lon, r.Stop.Lat})
+		feature.Properties.Stops = append(feature.Properties.Stops, BandStop{
+			StopID:   r.Stop.StopID,
+			StopName: r.Stop.StopName,
+			Arrival:  r.Arrival,
+			Seconds:  r.Seconds,
+		})
+	}
+	return collection
+}
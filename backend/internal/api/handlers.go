@@ -0,0 +1,361 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Durelius/next-week/internal/graph"
+	"github.com/gorilla/mux"
+)
+
+// maxDepartureWindow limits range queries, every departure in the window runs a search
+const maxDepartureWindow = 4 * time.Hour
+
+// avoidModePenalty is added to the cost of boarding a mode the user wants to avoid, in seconds
+const avoidModePenalty = 10 * 60
+
+func (s *Server) GetStopsByNameEndpoint(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	nodes := s.Graph().FindStopsByName(name)
+	stops := []graph.Stop{}
+	for _, v := range nodes {
+		stops = append(stops, *v.Metadata())
+	}
+
+	writeJSON(w, http.StatusOK, stops)
+}
+func (s *Server) GetPathEndpoint(w http.ResponseWriter, r *http.Request) {
+	slGraph := s.Graph()
+	fromStopID := mux.Vars(r)["from"]
+	toStopID := mux.Vars(r)["to"]
+	searchTime, err := searchTimeFromRequest(r, slGraph.Location())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	profile, err := profileFromRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	from, err := stopByID(slGraph, fromStopID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	to, err := stopByID(slGraph, toStopID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// ?arriveBy=true treats the time as the latest arrival instead of the departure
+	var path []*graph.TimedEdge
+	if arriveBy, _ := strconv.ParseBool(r.URL.Query().Get("arriveBy")); arriveBy {
+		path = slGraph.FindRouteArriveBy(from, to, searchTime, profile)
+	} else {
+		path = slGraph.FindRoute(from, to, searchTime, profile)
+	}
+
+	writeJSON(w, http.StatusOK, graph.NewJourney(path))
+}
+
+// GetPathBetweenPlacesEndpoint finds a route where the origin and destination
+// are either stops (?from=, ?to=) or coordinates (?fromLat=&fromLon=,
+// ?toLat=&toLon=), departing at ?time=HH:MM (default now) on ?date=
+func (s *Server) GetPathBetweenPlacesEndpoint(w http.ResponseWriter, r *http.Request) {
+	slGraph := s.Graph()
+	from, err := placeFromRequest(r, slGraph, "from")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	to, err := placeFromRequest(r, slGraph, "to")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	searchTime, err := searchTimeFromRequest(r, slGraph.Location())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	profile, err := profileFromRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	path := slGraph.FindRouteBetween(from, to, searchTime, profile)
+
+	writeJSON(w, http.StatusOK, graph.NewJourney(path))
+}
+
+// placeFromRequest reads the stop ID ?<prefix>= or the coordinate ?<prefix>Lat=&<prefix>Lon=
+func placeFromRequest(r *http.Request, slGraph *graph.SLGraph, prefix string) (graph.Place, error) {
+	query := r.URL.Query()
+	if stopID := query.Get(prefix); stopID != "" {
+		return placeByID(slGraph, stopID)
+	}
+	if !query.Has(prefix+"Lat") && !query.Has(prefix+"Lon") {
+		return graph.Place{}, newError(http.StatusBadRequest, CODE_INVALID_PARAMETER, "%s or %sLat and %sLon are required", prefix, prefix, prefix)
+	}
+	lat, lon, err := coordinate(query.Get(prefix+"Lat"), query.Get(prefix+"Lon"), prefix+"Lat", prefix+"Lon")
+	if err != nil {
+		return graph.Place{}, err
+	}
+	return graph.CoordinatePlace(lat, lon), nil
+}
+
+// placeByID looks up a stop or a station of the graph
+func placeByID(slGraph *graph.SLGraph, stopID string) (graph.Place, error) {
+	if v := slGraph.GetVertexByID(stopID); v != nil {
+		return graph.StopPlace(v), nil
+	}
+	if station := slGraph.GetStation(stopID); station != nil {
+		return graph.StationPlace(station), nil
+	}
+	return graph.Place{}, unknownStop(stopID)
+}
+
+// stopByID looks up a stop of the graph
+func stopByID(slGraph *graph.SLGraph, stopID string) (*graph.Vertex, error) {
+	v := slGraph.GetVertexByID(stopID)
+	if v == nil {
+		return nil, unknownStop(stopID)
+	}
+	return v, nil
+}
+
+// coordinate parses a latitude and longitude, named for the error
+func coordinate(latStr, lonStr, latName, lonName string) (float64, float64, error) {
+	lat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, invalidParameter(latName, latStr)
+	}
+	lon, err := strconv.ParseFloat(lonStr, 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, invalidParameter(lonName, lonStr)
+	}
+	return lat, lon, nil
+}
+
+// radiusFrom parses a search radius in meters, at most MAX_NEARBY_RADIUS
+func radiusFrom(radiusStr string) (float64, error) {
+	radius, err := strconv.ParseFloat(radiusStr, 64)
+	if err != nil || radius < 0 || radius > MAX_NEARBY_RADIUS {
+		return 0, newError(http.StatusBadRequest, CODE_INVALID_PARAMETER, "radius must be between 0 and %d meters, got %q", MAX_NEARBY_RADIUS, radiusStr)
+	}
+	return radius, nil
+}
+
+// windowEnd is the HH:MM until on the day of the search, at most
+// maxDepartureWindow after it
+func windowEnd(searchTime time.Time, untilStr string) (time.Time, error) {
+	untilHours, untilMinutes, err := parseClock(untilStr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	until := time.Date(searchTime.Year(), searchTime.Month(), searchTime.Day(), untilHours, untilMinutes, 0, 0, searchTime.Location())
+	if until.Before(searchTime) || until.Sub(searchTime) > maxDepartureWindow {
+		return time.Time{}, badTime("until must be within %s after the departure, got %q", maxDepartureWindow, untilStr)
+	}
+	return until, nil
+}
+
+// GetStopsNearEndpoint returns the stops within {radius} meters of a coordinate, closest first
+func (s *Server) GetStopsNearEndpoint(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	lat, lon, err := coordinate(vars["lat"], vars["lon"], "lat", "lon")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	radius, err := radiusFrom(vars["radius"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	stops := s.Graph().StopsWithinRadius(lat, lon, radius)
+	if stops == nil {
+		stops = []graph.NearbyStop{}
+	}
+
+	writeJSON(w, http.StatusOK, stops)
+}
+
+// GetJourneysEndpoint returns the Pareto-optimal alternatives by arrival time, transfers and walking
+func (s *Server) GetJourneysEndpoint(w http.ResponseWriter, r *http.Request) {
+	slGraph := s.Graph()
+	fromStopID := mux.Vars(r)["from"]
+	toStopID := mux.Vars(r)["to"]
+	searchTime, err := searchTimeFromRequest(r, slGraph.Location())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	profile, err := profileFromRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	from, err := stopByID(slGraph, fromStopID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	to, err := stopByID(slGraph, toStopID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// ?until=HH:MM returns every good journey departing between {time} and until
+	var options []*graph.RouteOption
+	if untilStr := r.URL.Query().Get("until"); untilStr != "" {
+		until, err := windowEnd(searchTime, untilStr)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		options = slGraph.FindRoutesInWindow(from, to, searchTime, until, profile)
+	} else {
+		options = slGraph.FindParetoRoutes(from, to, searchTime, profile)
+	}
+
+	writeJSON(w, http.StatusOK, options)
+}
+
+// ReloadEndpoint rebuilds the graph from the feed and swaps it in, requests
+// must send the admin token as "Authorization: Bearer <token>". Answers when
+// the new graph is in use.
+func (s *Server) ReloadEndpoint(w http.ResponseWriter, r *http.Request) {
+	auth := []byte(r.Header.Get("Authorization"))
+	if s.AdminToken == "" || subtle.ConstantTimeCompare(auth, []byte("Bearer "+s.AdminToken)) != 1 {
+		writeError(w, newError(http.StatusUnauthorized, CODE_UNAUTHORIZED, "a valid admin token is required"))
+		return
+	}
+	slGraph, err := s.Reload()
+	if err != nil {
+		writeError(w, fmt.Errorf("couldn't reload graph: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]uint32{
+		"vertices": slGraph.Order(),
+		"edges":    slGraph.Size(),
+	})
+}
+
+// searchTimeFromRequest combines the HH:MM {time} path variable or ?time= with
+// the optional ?date=YYYY-MM-DD, which defaults to today in the timezone of the
+// feed. Without a time it's now.
+func searchTimeFromRequest(r *http.Request, loc *time.Location) (time.Time, error) {
+	date := time.Now().In(loc)
+	clock, ok := mux.Vars(r)["time"]
+	if !ok {
+		clock = r.URL.Query().Get("time")
+	}
+	if clock == "" {
+		clock = date.Format("15:04")
+	}
+	startTimeHours, startTimeMinutes, err := parseClock(clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		date, err = time.ParseInLocation(time.DateOnly, dateStr, loc)
+		if err != nil {
+			return time.Time{}, badTime("date must be YYYY-MM-DD, got %q", dateStr)
+		}
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), startTimeHours, startTimeMinutes, 0, 0, loc), nil
+}
+
+// profileFromRequest builds the routing profile from the query, starting from
+// ?profile=default|reducedMobility|luggage. Individual values can be overridden
+// with walkSpeed (meters per minute), maxWalk (meters), walkPenalty,
+// transferPenalty and minTransfer (minutes), and exclude or avoid take a comma
+// separated list of modes to never use or to use only if clearly better
+func profileFromRequest(r *http.Request) (graph.RoutingProfile, error) {
+	query := r.URL.Query()
+	profile, ok := graph.ProfileByName(query.Get("profile"))
+	if !ok {
+		return profile, invalidParameter("profile", query.Get("profile"))
+	}
+	if v := query.Get("walkSpeed"); v != "" {
+		speed, err := strconv.ParseFloat(v, 64)
+		if err != nil || speed <= 0 {
+			return profile, invalidParameter("walkSpeed", v)
+		}
+		profile.WalkSpeed = speed
+	}
+	if v := query.Get("maxWalk"); v != "" {
+		meters, err := strconv.ParseFloat(v, 64)
+		if err != nil || meters < 0 {
+			return profile, invalidParameter("maxWalk", v)
+		}
+		profile.MaxWalkDistance = meters
+	}
+	minutes := map[string]*int{
+		"walkPenalty":     &profile.WalkPenalty,
+		"transferPenalty": &profile.TransferPenalty,
+		"minTransfer":     &profile.MinTransferTime,
+	}
+	for name, field := range minutes {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		m, err := strconv.Atoi(v)
+		if err != nil || m < 0 {
+			return profile, invalidParameter(name, v)
+		}
+		*field = m * 60
+	}
+	if v := query.Get("exclude"); v != "" {
+		profile.ExcludedModes = make(map[graph.Mode]bool)
+		for _, name := range strings.Split(v, ",") {
+			mode, err := graph.ParseMode(name)
+			if err != nil {
+				return profile, invalidParameter("exclude", name)
+			}
+			profile.ExcludedModes[mode] = true
+		}
+	}
+	if v := query.Get("avoid"); v != "" {
+		profile.ModePenalties = make(map[graph.Mode]int)
+		for _, name := range strings.Split(v, ",") {
+			mode, err := graph.ParseMode(name)
+			if err != nil {
+				return profile, invalidParameter("avoid", name)
+			}
+			profile.ModePenalties[mode] = avoidModePenalty
+		}
+	}
+	return profile, nil
+}
+
+// parseClock parses a HH:MM time of day, the hour may have one digit
+func parseClock(clock string) (int, int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, 0, badTime("time must be HH:MM, got %q", clock)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statusOf is the HTTP status each error code is sent with
+var statusOf = map[string]int{
+	CODE_INVALID_PARAMETER: http.StatusBadRequest,
+	CODE_BAD_TIME:          http.StatusBadRequest,
+	CODE_UNKNOWN_STOP:      http.StatusNotFound,
+	CODE_NO_ROUTE:          http.StatusNotFound,
+	CODE_UNAUTHORIZED:      http.StatusUnauthorized,
+	CODE_INTERNAL:          http.StatusInternalServerError,
+}
+
+// OpenAPI returns the OpenAPI 3 document of the versioned API, generated from
+// its endpoints and the Go types they answer with
+func OpenAPI() map[string]any {
+	schemas := make(schemas)
+	errorSchema := schemas.of(reflect.TypeFor[ErrorResponse]())
+	paths := make(map[string]any)
+	for _, e := range endpoints() {
+		parameters := []any{}
+		for _, p := range e.Params {
+			schema := map[string]any{"type": p.Type}
+			if p.Format != "" {
+				schema["format"] = p.Format
+			}
+			parameters = append(parameters, map[string]any{
+				"name":        p.Name,
+				"in":          "query",
+				"required":    p.Required,
+				"description": p.Description,
+				"schema":      schema,
+			})
+		}
+		responses := map[string]any{
+			"200": map[string]any{
+				"description": "OK",
+				"content":     jsonContent(schemas.of(reflect.TypeOf(e.Response))),
+			},
+		}
+		// the codes of each status, an endpoint may fail with several
+		codes := make(map[int][]string)
+		for _, code := range append(e.Errors, CODE_INTERNAL) {
+			codes[statusOf[code]] = append(codes[statusOf[code]], code)
+		}
+		for status, codes := range codes {
+			responses[strconv.Itoa(status)] = map[string]any{
+				"description": "error codes: " + strings.Join(codes, ", "),
+				"content":     jsonContent(errorSchema),
+			}
+		}
+		paths[e.Path] = map[string]any{
+			strings.ToLower(e.Method): map[string]any{
+				"summary":    e.Summary,
+				"parameters": parameters,
+				"responses":  responses,
+			},
+		}
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "next-week journey planner",
+			"version": "1",
+		},
+		"servers":    []any{map[string]any{"url": API_PREFIX}},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// schemas collects the schemas of named structs, referenced by name
+type schemas map[string]any
+
+var textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+
+// of returns the JSON schema of a Go type as encoding/json writes it
+func (sc schemas) of(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if t.Implements(textMarshaler) || reflect.PointerTo(t).Implements(textMarshaler) {
+		return map[string]any{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return sc.of(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": sc.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": sc.of(t.Elem())}
+	case reflect.Struct:
+		if _, ok := sc[t.Name()]; !ok {
+			sc[t.Name()] = nil // mark it before the fields, a type may refer to itself
+			properties := make(map[string]any)
+			sc.addFields(t, properties)
+			sc[t.Name()] = map[string]any{"type": "object", "properties": properties}
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// addFields adds the JSON fields of a struct, embedded structs are inlined
+func (sc schemas) addFields(t reflect.Type, properties map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				sc.addFields(embedded, properties)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = sc.of(f.Type)
+	}
+}
@@ -0,0 +1,94 @@
+// Package api serves the journey planner over HTTP
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Durelius/next-week/internal/graph"
+	"github.com/Durelius/next-week/internal/gtfs"
+	"github.com/gorilla/mux"
+)
+
+// Server answers requests from the graph it holds. A reload builds a new
+// graph and swaps it in, so a request that got the old graph finishes its
+// search on it.
+type Server struct {
+	graph    atomic.Pointer[graph.SLGraph]
+	reloadMu sync.Mutex // one build at a time
+
+	// where Reload builds the graph from, see graph.Build
+	Source       string
+	SnapshotPath string
+	// the bearer token of the admin endpoints, they are disabled without one
+	AdminToken string
+}
+
+// NewServer creates a server answering from g
+func NewServer(g *graph.SLGraph) *Server {
+	s := &Server{}
+	s.graph.Store(g)
+	return s
+}
+
+// Graph returns the graph in use. Handlers should call it once and keep the
+// graph, a reload may swap it in between calls.
+func (s *Server) Graph() *graph.SLGraph {
+	return s.graph.Load()
+}
+
+// SetGraph swaps in a new graph
+func (s *Server) SetGraph(g *graph.SLGraph) {
+	s.graph.Store(g)
+}
+
+// Reload builds a new graph from the feed at Source and swaps it in. On error
+// the current graph stays in use.
+func (s *Server) Reload() (*graph.SLGraph, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	start := time.Now()
+	g, err := graph.Build(s.Source, s.SnapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	s.SetGraph(g)
+	log.Printf("reloaded graph from %s in %s, %d vertices and %d edges", s.Source, time.Since(start).Round(time.Millisecond), g.Order(), g.Size())
+	return g, nil
+}
+
+// Handler returns the routes of the server allowing cross-origin requests
+func (s *Server) Handler() http.Handler {
+	return corsMiddleware(s.Router())
+}
+
+// Router returns the routes of the server: the versioned API below
+// API_PREFIX, and the older routes taking path variables
+func (s *Server) Router() *mux.Router {
+	r := mux.NewRouter()
+	v1 := r.PathPrefix(API_PREFIX).Subrouter()
+	for _, e := range endpoints() {
+		v1.HandleFunc(e.Path, s.validated(e)).Methods(e.Method)
+	}
+	r.HandleFunc("/stopbyname/{name}", s.GetStopsByNameEndpoint).Methods("GET")
+	r.HandleFunc("/path", s.GetPathBetweenPlacesEndpoint).Methods("GET")
+	r.HandleFunc("/path/{from}/{to}/{time}", s.GetPathEndpoint).Methods("GET")
+	r.HandleFunc("/journeys/{from}/{to}/{time}", s.GetJourneysEndpoint).Methods("GET")
+	r.HandleFunc("/stopsnear/{lat}/{lon}/{radius}", s.GetStopsNearEndpoint).Methods("GET")
+	r.HandleFunc("/admin/reload", s.ReloadEndpoint).Methods("POST")
+	return r
+}
+
+// Watch reloads the graph whenever the feed at Source changes, checking every
+// interval until ctx is done
+func (s *Server) Watch(ctx context.Context, interval time.Duration) {
+	gtfs.Watch(ctx, s.Source, interval, func() {
+		if _, err := s.Reload(); err != nil {
+			log.Printf("Couldn't reload graph from %s, keeping the current one, err: %v", s.Source, err)
+		}
+	})
+}
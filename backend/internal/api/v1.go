@@ -0,0 +1,384 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// API_PREFIX is where the versioned API is served
+const API_PREFIX = "/api/v1"
+
+// radius of the nearby stops search in meters, the default when none is given
+const (
+	DEFAULT_RADIUS    = 500
+	MAX_NEARBY_RADIUS = 2000
+)
+
+// limits of the stop name search, the default suits autocompletion
+const (
+	DEFAULT_STOP_LIMIT = 10
+	MAX_STOP_LIMIT     = 100
+)
+
+// param is a query parameter of an endpoint
+type param struct {
+	Name        string
+	Type        string // JSON schema type: string, number, integer or boolean
+	Format      string
+	Required    bool
+	Description string
+}
+
+// endpoint is a route of the versioned API. The same description validates
+// requests and generates the OpenAPI document, so they can't disagree.
+type endpoint struct {
+	Method   string
+	Path     string // below API_PREFIX
+	Summary  string
+	Params   []param
+	Response any      // a value of the type answered with
+	Errors   []string // codes the endpoint may fail with
+	handler  func(s *Server, w http.ResponseWriter, r *http.Request) error
+}
+
+func placeParams(prefix, role string) []param {
+	return []param{
+		{Name: prefix, Type: "string", Description: "stop or station ID of the " + role},
+		{Name: prefix + "Lat", Type: "number", Format: "double", Description: "latitude of the " + role + ", instead of a stop"},
+		{Name: prefix + "Lon", Type: "number", Format: "double", Description: "longitude of the " + role + ", instead of a stop"},
+	}
+}
+
+// the parameters read by searchTimeFromRequest and profileFromRequest
+var (
+	timeParams = []param{
+		{Name: "time", Type: "string", Description: "HH:MM, defaults to now"},
+		{Name: "date", Type: "string", Format: "date", Description: "YYYY-MM-DD, defaults to today in the timezone of the feed"},
+	}
+	profileParams = []param{
+		{Name: "profile", Type: "string", Description: "default, reducedMobility or luggage"},
+		{Name: "walkSpeed", Type: "number", Description: "walking speed in meters per minute"},
+		{Name: "maxWalk", Type: "number", Description: "longest walk in meters"},
+		{Name: "walkPenalty", Type: "integer", Description: "minutes added to the cost of every walk"},
+		{Name: "transferPenalty", Type: "integer", Description: "minutes added to the cost of every transfer"},
+		{Name: "minTransfer", Type: "integer", Description: "minimum minutes to change vehicle"},
+		{Name: "exclude", Type: "string", Description: "comma separated modes never to use"},
+		{Name: "avoid", Type: "string", Description: "comma separated modes to use only if clearly better"},
+	}
+)
+
+func concat(groups ...[]param) []param {
+	var params []param
+	for _, group := range groups {
+		params = append(params, group...)
+	}
+	return params
+}
+
+// endpoints lists the routes of the versioned API
+func endpoints() []endpoint {
+	return []endpoint{
+		{
+			Method:  http.MethodGet,
+			Path:    "/stops",
+			Summary: "Stops matching a name, best match first",
+			Params: []param{
+				{Name: "name", Type: "string", Required: true, Description: "the stop name or the start of its words, ignoring case, diacritics and small typos"},
+				{Name: "limit", Type: "integer", Description: "most stops to return, defaults to " + strconv.Itoa(DEFAULT_STOP_LIMIT) + " and at most " + strconv.Itoa(MAX_STOP_LIMIT)},
+			},
+			Response: []graph.Stop{},
+			Errors:   []string{CODE_INVALID_PARAMETER},
+			handler:  (*Server).getStops,
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/stations",
+			Summary: "Stations matching a name, best match first. A station groups the platforms sharing a name.",
+			Params: []param{
+				{Name: "name", Type: "string", Required: true, Description: "the station name or the start of its words, ignoring case, diacritics and small typos"},
+				{Name: "limit", Type: "integer", Description: "most stations to return, defaults to " + strconv.Itoa(DEFAULT_STOP_LIMIT) + " and at most " + strconv.Itoa(MAX_STOP_LIMIT)},
+			},
+			Response: []graph.Station{},
+			Errors:   []string{CODE_INVALID_PARAMETER},
+			handler:  (*Server).getStations,
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/stops/near",
+			Summary: "Stops within a radius of a coordinate, closest first",
+			Params: []param{
+				{Name: "lat", Type: "number", Format: "double", Required: true},
+				{Name: "lon", Type: "number", Format: "double", Required: true},
+				{Name: "radius", Type: "number", Description: "meters, at most " + strconv.Itoa(MAX_NEARBY_RADIUS) + ", defaults to " + strconv.Itoa(DEFAULT_RADIUS)},
+			},
+			Response: []graph.NearbyStop{},
+			Errors:   []string{CODE_INVALID_PARAMETER},
+			handler:  (*Server).getStopsNear,
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/journey",
+			Summary: "The best journey between two stops, stations or coordinates",
+			Params: concat(placeParams("from", "origin"), placeParams("to", "destination"), timeParams,
+				[]param{{Name: "arriveBy", Type: "boolean", Description: "the time is the latest arrival instead of the departure, only between stops and stations"}},
+				profileParams),
+			Response: graph.Journey{},
+			Errors:   []string{CODE_INVALID_PARAMETER, CODE_BAD_TIME, CODE_UNKNOWN_STOP, CODE_NO_ROUTE},
+			handler:  (*Server).getJourney,
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/journeys",
+			Summary: "The journeys between two stops or stations that are best by arrival, transfers or walking",
+			Params: concat([]param{
+				{Name: "from", Type: "string", Required: true, Description: "stop or station ID of the origin"},
+				{Name: "to", Type: "string", Required: true, Description: "stop or station ID of the destination"},
+			}, timeParams,
+				[]param{{Name: "until", Type: "string", Description: "HH:MM, every good journey departing between time and until"}},
+				profileParams),
+			Response: []graph.RouteOption{},
+			Errors:   []string{CODE_INVALID_PARAMETER, CODE_BAD_TIME, CODE_UNKNOWN_STOP, CODE_NO_ROUTE},
+			handler:  (*Server).getJourneys,
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/isochrone",
+			Summary: "The stops reachable from a stop, station or coordinate as GeoJSON, grouped into bands of minutes by the earliest arrival",
+			Params: concat(placeParams("from", "origin"), timeParams,
+				[]param{{Name: "bands", Type: "string", Description: "comma separated increasing minutes the bands end at, the last is how far to search. At most " + strconv.Itoa(MAX_ISOCHRONE_BANDS) + " bands up to " + strconv.Itoa(MAX_ISOCHRONE_MINUTES) + " minutes, defaults to " + bandList(defaultIsochroneBands())}},
+				profileParams),
+			Response: FeatureCollection{},
+			Errors:   []string{CODE_INVALID_PARAMETER, CODE_BAD_TIME, CODE_UNKNOWN_STOP},
+			handler:  (*Server).getIsochrone,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/openapi.json",
+			Summary:  "This document",
+			Response: map[string]any{},
+			handler:  (*Server).getOpenAPI,
+		},
+	}
+}
+
+// validated checks the query of a request against the parameters of the
+// endpoint before handing it on, unknown and missing parameters are errors
+func (s *Server) validated(e endpoint) http.HandlerFunc {
+	known := make(map[string]bool, len(e.Params))
+	for _, p := range e.Params {
+		known[p.Name] = true
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		for name := range query {
+			if !known[name] {
+				writeError(w, newError(http.StatusBadRequest, CODE_INVALID_PARAMETER, "unknown parameter %q", name))
+				return
+			}
+		}
+		for _, p := range e.Params {
+			if p.Required && query.Get(p.Name) == "" {
+				writeError(w, newError(http.StatusBadRequest, CODE_INVALID_PARAMETER, "%s is required", p.Name))
+				return
+			}
+		}
+		if err := e.handler(s, w, r); err != nil {
+			writeError(w, err)
+		}
+	}
+}
+
+// limitFromRequest reads the limit of a name search
+func limitFromRequest(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return DEFAULT_STOP_LIMIT, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 || limit > MAX_STOP_LIMIT {
+		return 0, invalidParameter("limit", v)
+	}
+	return limit, nil
+}
+
+func (s *Server) getStops(w http.ResponseWriter, r *http.Request) error {
+	limit, err := limitFromRequest(r)
+	if err != nil {
+		return err
+	}
+	stops := []graph.Stop{}
+	for _, v := range s.Graph().SearchStops(r.URL.Query().Get("name"), limit) {
+		stops = append(stops, *v.Metadata())
+	}
+	writeJSON(w, http.StatusOK, stops)
+	return nil
+}
+
+func (s *Server) getStations(w http.ResponseWriter, r *http.Request) error {
+	limit, err := limitFromRequest(r)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, s.Graph().SearchStations(r.URL.Query().Get("name"), limit))
+	return nil
+}
+
+func (s *Server) getStopsNear(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	lat, lon, err := coordinate(query.Get("lat"), query.Get("lon"), "lat", "lon")
+	if err != nil {
+		return err
+	}
+	radius := float64(DEFAULT_RADIUS)
+	if query.Has("radius") {
+		if radius, err = radiusFrom(query.Get("radius")); err != nil {
+			return err
+		}
+	}
+	stops := s.Graph().StopsWithinRadius(lat, lon, radius)
+	if stops == nil {
+		stops = []graph.NearbyStop{}
+	}
+	writeJSON(w, http.StatusOK, stops)
+	return nil
+}
+
+func (s *Server) getJourney(w http.ResponseWriter, r *http.Request) error {
+	slGraph := s.Graph()
+	from, err := placeFromRequest(r, slGraph, "from")
+	if err != nil {
+		return err
+	}
+	to, err := placeFromRequest(r, slGraph, "to")
+	if err != nil {
+		return err
+	}
+	searchTime, err := searchTimeFromRequest(r, slGraph.Location())
+	if err != nil {
+		return err
+	}
+	profile, err := profileFromRequest(r)
+	if err != nil {
+		return err
+	}
+	arriveBy := false
+	if v := r.URL.Query().Get("arriveBy"); v != "" {
+		if arriveBy, err = strconv.ParseBool(v); err != nil {
+			return invalidParameter("arriveBy", v)
+		}
+	}
+	var path []*graph.TimedEdge
+	if arriveBy {
+		if from.IsCoordinate() || to.IsCoordinate() {
+			return newError(http.StatusBadRequest, CODE_INVALID_PARAMETER, "arriveBy needs a stop or station as origin and destination")
+		}
+		path = slGraph.FindRouteArriveByBetween(from, to, searchTime, profile)
+	} else {
+		path = slGraph.FindRouteBetween(from, to, searchTime, profile)
+	}
+	journey := graph.NewJourney(path)
+	if journey == nil {
+		return noRoute()
+	}
+	writeJSON(w, http.StatusOK, journey)
+	return nil
+}
+
+func (s *Server) getJourneys(w http.ResponseWriter, r *http.Request) error {
+	slGraph := s.Graph()
+	query := r.URL.Query()
+	from, err := placeByID(slGraph, query.Get("from"))
+	if err != nil {
+		return err
+	}
+	to, err := placeByID(slGraph, query.Get("to"))
+	if err != nil {
+		return err
+	}
+	searchTime, err := searchTimeFromRequest(r, slGraph.Location())
+	if err != nil {
+		return err
+	}
+	profile, err := profileFromRequest(r)
+	if err != nil {
+		return err
+	}
+	var options []*graph.RouteOption
+	if untilStr := query.Get("until"); untilStr != "" {
+		until, err := windowEnd(searchTime, untilStr)
+		if err != nil {
+			return err
+		}
+		options = slGraph.FindRoutesInWindowBetween(from, to, searchTime, until, profile)
+	} else {
+		options = slGraph.FindParetoRoutesBetween(from, to, searchTime, profile)
+	}
+	if len(options) == 0 {
+		return noRoute()
+	}
+	writeJSON(w, http.StatusOK, options)
+	return nil
+}
+
+func (s *Server) getIsochrone(w http.ResponseWriter, r *http.Request) error {
+	slGraph := s.Graph()
+	from, err := placeFromRequest(r, slGraph, "from")
+	if err != nil {
+		return err
+	}
+	searchTime, err := searchTimeFromRequest(r, slGraph.Location())
+	if err != nil {
+		return err
+	}
+	profile, err := profileFromRequest(r)
+	if err != nil {
+		return err
+	}
+	bands, err := bandsFromRequest(r)
+	if err != nil {
+		return err
+	}
+	budget := time.Duration(bands[len(bands)-1]) * time.Minute
+	reached := slGraph.Isochrone(from, searchTime, budget, profile)
+	writeJSON(w, http.StatusOK, isochroneBands(reached, bands))
+	return nil
+}
+
+// bandsFromRequest reads ?bands=, increasing minutes separated by commas
+func bandsFromRequest(r *http.Request) ([]int, error) {
+	v := r.URL.Query().Get("bands")
+	if v == "" {
+		return defaultIsochroneBands(), nil
+	}
+	fields := strings.Split(v, ",")
+	if len(fields) > MAX_ISOCHRONE_BANDS {
+		return nil, newError(http.StatusBadRequest, CODE_INVALID_PARAMETER, "at most %d bands, got %q", MAX_ISOCHRONE_BANDS, v)
+	}
+	bands := make([]int, len(fields))
+	for i, field := range fields {
+		minutes, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || minutes < 1 || minutes > MAX_ISOCHRONE_MINUTES || i > 0 && minutes <= bands[i-1] {
+			return nil, newError(http.StatusBadRequest, CODE_INVALID_PARAMETER, "bands must be increasing minutes between 1 and %d, got %q", MAX_ISOCHRONE_MINUTES, v)
+		}
+		bands[i] = minutes
+	}
+	return bands, nil
+}
+
+// bandList is isochrone bands for humans, 15,30,45
+func bandList(bands []int) string {
+	minutes := make([]string, len(bands))
+	for i, m := range bands {
+		minutes[i] = strconv.Itoa(m)
+	}
+	return strings.Join(minutes, ",")
+}
+
+func (s *Server) getOpenAPI(w http.ResponseWriter, r *http.Request) error {
+	writeJSON(w, http.StatusOK, OpenAPI())
+	return nil
+}
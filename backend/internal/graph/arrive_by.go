@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"container/heap"
+	"log"
+	"time"
+
+	pq "github.com/Durelius/next-week/internal/priority_queue"
+)
+
+// FindRouteArriveBy finds the route between two stops that reaches the
+// destination no later than the given arrival time while leaving the start as
+// late as possible. It is FindRoute run backwards in time, expanding incoming
+// edges from the destination and maximizing the departure time.
+func (graph *SLGraph) FindRouteArriveBy(start *Vertex, destination *Vertex, arrival time.Time, profile RoutingProfile) []*TimedEdge {
+	return graph.FindRouteArriveByBetween(StopPlace(start), StopPlace(destination), arrival, profile)
+}
+
+// FindRouteArriveByBetween is FindRouteArriveBy between stops or stations (see Place)
+func (graph *SLGraph) FindRouteArriveByBetween(from, to Place, arrival time.Time, profile RoutingProfile) []*TimedEdge {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	starts := make(map[string]Endpoint)
+	for _, v := range placeVertices(from) {
+		starts[v.label] = Endpoint{Vertex: v}
+	}
+	destinations := placeVertices(to)
+	if len(starts) == 0 || len(destinations) == 0 {
+		return nil
+	}
+	ctx := graph.newSearchContext(arrival, profile)
+	arrivalTime := ctx.Seconds(arrival)
+	// searching backwards, the estimate is to the closest start
+	estimate := estimateTo(starts)
+	// the cost grows the earlier a stop has to be left to make it in time
+	open := make(pq.PriorityQueue, 0)
+	heap.Init(&open)
+	closed := make(map[string]bool)
+	bestG := make(map[string]int)
+	ends := make(map[string]bool)
+	for _, destination := range destinations {
+		ends[destination.label] = true
+		bestG[destination.label] = 0
+		heap.Push(&open, pq.NewItem(destination.label, 0, 0))
+	}
+	goesTo := make(map[string]hop) //"From this stop, we leave using this edge"
+	for len(open) > 0 {
+		current := heap.Pop(&open).(*pq.Item)
+		currentStop := graph.vertices[current.Value()]
+
+		nextTripID, nextWait := "", 0
+		latestTime := arrivalTime
+		if next, ok := goesTo[current.Value()]; ok {
+			nextTripID, nextWait = next.edge.Metadata.TripID, next.edge.boardingWait()
+			latestTime = next.departure
+		}
+		if _, ok := starts[current.Value()]; ok {
+
+			var path []*TimedEdge
+			currentID := current.Value()
+
+			//find destination
+			for !ends[currentID] {
+				next, ok := goesTo[currentID]
+				if !ok {
+					log.Println("error: Didn't find goes to")
+					return nil
+				}
+
+				path = append(path, &TimedEdge{
+					Edge:          next.edge,
+					DepartureTime: ctx.Time(next.departure),
+					ArrivalTime:   ctx.Time(next.arrival),
+				})
+				currentID = next.edge.dest.label // go forwards
+			}
+			return path
+		}
+
+		if closed[current.Value()] {
+			continue
+		}
+
+		closed[current.Value()] = true
+		for _, edge := range currentStop.incoming {
+			neighborID := edge.source.label
+			run, cost, ok := edge.calculateReverseG(ctx, latestTime, nextTripID, nextWait)
+			if !ok {
+				continue
+			}
+			if _, ok := starts[neighborID]; ok {
+				cost += edge.boardingWait() // the first vehicle is boarded at the start
+			}
+			newG := current.G() + cost
+			if best, exists := bestG[neighborID]; !exists || newG < best {
+				bestG[neighborID] = newG
+				f := newG + estimate(graph.vertices[neighborID])
+				heap.Push(&open, pq.NewItem(neighborID, newG, f))
+				goesTo[neighborID] = run
+			}
+		}
+
+	}
+	return nil
+}
@@ -3,39 +3,205 @@ package graph
 import (
 	"math"
 	"strconv"
+	"time"
 )
 
+// searchContext is what edges are evaluated against during a search
+type searchContext struct {
+	*clock
+	graph     *SLGraph
+	profile   *RoutingProfile
+	transfers *TransferRules
+}
+
+func (graph *SLGraph) newSearchContext(t time.Time, profile RoutingProfile) *searchContext {
+	return &searchContext{
+		clock:     newClock(t, graph.calendar),
+		graph:     graph,
+		profile:   &profile,
+		transfers: graph.transfers,
+	}
+}
+
 func calculateH(from *Stop, destination *Stop) int {
-	dist, err := ApproxDistanceMeters(from, destination)
-	if err != nil {
+	if !from.HasCoordinates() || !destination.HasCoordinates() {
 		return 0
 	}
+	dist := DistanceMeters(from.Lat, from.Lon, destination.Lat, destination.Lon)
 	// tunnelbana top speed 70 km/h = 1166 meters per minute
 	// this is heuristics, so not accurate just a guess
-	return int(dist / 1166.0)
+	return int(dist / 1166.0 * 60)
+}
+
+// usable reports whether the profile allows using the edge at all
+func (e *Edge) usable(profile *RoutingProfile) bool {
+	if e.Metadata.TransferType == WALK_EDGE {
+		return e.Metadata.Distance <= profile.MaxWalkDistance
+	}
+	return !profile.ExcludedModes[e.Metadata.Mode]
+}
+
+// walkSeconds is the time it takes to walk a walking edge with the profile,
+// edges without a distance keep their precomputed walking time
+func (e *Edge) walkSeconds(profile *RoutingProfile) int {
+	if e.Metadata.Distance == 0 {
+		return e.Metadata.Arrival
+	}
+	return profile.walkSeconds(e.Metadata.Distance)
+}
+
+// changesTrip reports whether taking the edge after arriving with currentTripID means changing vehicle
+func (e *Edge) changesTrip(currentTripID string) bool {
+	return currentTripID != "" && e.Metadata.TransferType == COMMUTE_EDGE && currentTripID != e.Metadata.TripID
 }
-func (e *Edge) calculateG(currentTime int, currentTripID string) int {
+
+// boardingWait is the time counted for waiting when boarding a run of the
+// edge. Runs of frequency based trips without exact times only tell how often
+// a vehicle comes, so the search doesn't count on one sooner than half a
+// headway after reaching the stop.
+func (e *Edge) boardingWait() int {
+	return e.Metadata.Headway / 2
+}
+
+// nextDeparture finds the first run of the edge leaving at or after currentTime,
+// returning its departure and arrival in search seconds. Walking edges can be
+// used right away.
+func (e *Edge) nextDeparture(ctx *searchContext, currentTime int) (int, int, bool) {
 	if e.Metadata.TransferType == WALK_EDGE {
-		//penalty 5 here for walking
-		return currentTime + e.Metadata.Arrival + 5
+		walk, ok := ctx.walkTime(e)
+		return currentTime, currentTime + walk, ok
 	}
+	// first service day whose run of the trip could still be caught, one day
+	// earlier to be safe around daylight saving time changes
+	firstDay := floorDiv(currentTime-e.Metadata.Departure, SECONDS_PER_DAY)
+	for day := firstDay - 1; day <= firstDay+serviceDayLookahead; day++ {
+		offset := ctx.dayOffset(day)
+		departure := offset + e.Metadata.Departure
+		if departure < currentTime || !ctx.runs(day, e.Metadata.ServiceID) {
+			continue
+		}
+		return departure, offset + e.Metadata.Arrival, true
+	}
+	return 0, 0, false
+}
 
-	if e.Metadata.Departure < currentTime {
-		return -1
+// calculateG returns the run of the edge taken when at its start at currentTime
+// having arrived with currentTripID, and the cost of taking it in seconds: the
+// time until arriving plus the profile's penalties
+func (e *Edge) calculateG(ctx *searchContext, currentTime int, currentTripID string) (hop, int, bool) {
+	if !e.usable(ctx.profile) {
+		return hop{}, 0, false
 	}
-	waitTime := e.Metadata.Departure - currentTime
-	travelTime := e.Metadata.Arrival - e.Metadata.Departure
+	earliest := currentTime
 	penalty := 0
-	if currentTripID != "" && e.Metadata.TripID != "" && currentTripID != e.Metadata.TripID {
-		//penalty 5 here for changing line
-		penalty = 5
+	if e.Metadata.TransferType == WALK_EDGE {
+		penalty = ctx.profile.WalkPenalty
+	} else if currentTripID != e.Metadata.TripID {
+		earliest += e.boardingWait()
+		penalty = ctx.profile.ModePenalties[e.Metadata.Mode]
+		if e.changesTrip(currentTripID) {
+			change, ok := ctx.minChangeTime(e.source.label)
+			if !ok {
+				return hop{}, 0, false
+			}
+			earliest += change
+			penalty += ctx.profile.TransferPenalty
+		}
 	}
-	return currentTime + waitTime + travelTime + penalty
+	departure, arrival, ok := e.nextDeparture(ctx, earliest)
+	if !ok {
+		return hop{}, 0, false
+	}
+	return hop{edge: e, departure: departure, arrival: arrival}, arrival - currentTime + penalty, true
+}
+
+// departuresBetween returns every run of a commute edge departing within
+// [from, to], in search seconds
+func (e *Edge) departuresBetween(ctx *searchContext, from, to int) []int {
+	if e.Metadata.TransferType != COMMUTE_EDGE {
+		return nil
+	}
+	var departures []int
+	firstDay := floorDiv(from-e.Metadata.Departure, SECONDS_PER_DAY)
+	lastDay := floorDiv(to-e.Metadata.Departure, SECONDS_PER_DAY)
+	for day := firstDay - 1; day <= lastDay+1; day++ {
+		departure := ctx.dayOffset(day) + e.Metadata.Departure
+		if departure < from || departure > to || !ctx.runs(day, e.Metadata.ServiceID) {
+			continue
+		}
+		departures = append(departures, departure)
+	}
+	return departures
+}
+
+// previousArrival finds the last run of the edge arriving at or before
+// latestArrival, returning its departure and arrival in search seconds. Walking
+// edges are started just in time.
+func (e *Edge) previousArrival(ctx *searchContext, latestArrival int) (int, int, bool) {
+	if e.Metadata.TransferType == WALK_EDGE {
+		walk, ok := ctx.walkTime(e)
+		return latestArrival - walk, latestArrival, ok
+	}
+	lastDay := floorDiv(latestArrival-e.Metadata.Arrival, SECONDS_PER_DAY)
+	for day := lastDay + 1; day >= lastDay-serviceDayLookahead; day-- {
+		offset := ctx.dayOffset(day)
+		arrival := offset + e.Metadata.Arrival
+		if arrival > latestArrival || !ctx.runs(day, e.Metadata.ServiceID) {
+			continue
+		}
+		return offset + e.Metadata.Departure, arrival, true
+	}
+	return 0, 0, false
+}
+
+// calculateReverseG is calculateG for searching backwards in time. Given the
+// latest time the end of the edge must be reached and the trip taken from
+// there, boarded with nextWait, it returns the latest run of the edge and the
+// cost of taking it: the time between its departure and latestTime plus the
+// profile's penalties
+func (e *Edge) calculateReverseG(ctx *searchContext, latestTime int, nextTripID string, nextWait int) (hop, int, bool) {
+	if !e.usable(ctx.profile) {
+		return hop{}, 0, false
+	}
+	latest := latestTime
+	penalty := 0
+	if e.Metadata.TripID != nextTripID {
+		latest -= nextWait
+	}
+	if e.Metadata.TransferType == WALK_EDGE {
+		penalty = ctx.profile.WalkPenalty
+	} else if e.Metadata.TripID != nextTripID {
+		penalty = ctx.profile.ModePenalties[e.Metadata.Mode]
+		if nextTripID != "" {
+			// arriving on another trip than the one continued with is a change
+			change, ok := ctx.minChangeTime(e.dest.label)
+			if !ok {
+				return hop{}, 0, false
+			}
+			latest -= change
+			penalty += ctx.profile.TransferPenalty
+		}
+	}
+	departure, arrival, ok := e.previousArrival(ctx, latest)
+	if !ok {
+		return hop{}, 0, false
+	}
+	return hop{edge: e, departure: departure, arrival: arrival}, latestTime - departure + penalty, true
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
 }
 
 // ApproxDistanceMeters calculates the distance in meters between to stops  by their coordinates
-// algorithm implementation found at https://github.com/daveroberts0321/distancecalculator/blob/main/distancecalculator.go
 func ApproxDistanceMeters(from *Stop, to *Stop) (float64, error) {
+	if from.HasCoordinates() && to.HasCoordinates() {
+		return DistanceMeters(from.Lat, from.Lon, to.Lat, to.Lon), nil
+	}
 	fromLat, err := strconv.ParseFloat(from.StopLatitude, 64)
 	if err != nil {
 		return 0, err
@@ -52,10 +218,16 @@ func ApproxDistanceMeters(from *Stop, to *Stop) (float64, error) {
 	if err != nil {
 		return 0, err
 	}
+	return DistanceMeters(fromLat, fromLong, toLat, toLong), nil
+}
+
+// DistanceMeters calculates the distance in meters between two coordinates
+// algorithm implementation found at https://github.com/daveroberts0321/distancecalculator/blob/main/distancecalculator.go
+func DistanceMeters(fromLat, fromLong, toLat, toLong float64) float64 {
 	// Calculate distances
 	lat1 := fromLat * math.Pi / 180
 	long1 := fromLong * math.Pi / 180
-	r := 6378100.0 // Earth radius in METERS
+	r := EARTH_RADIUS
 
 	lat2 := toLat * math.Pi / 180
 	long2 := toLong * math.Pi / 180
@@ -63,5 +235,5 @@ func ApproxDistanceMeters(from *Stop, to *Stop) (float64, error) {
 	// Haversine formula to calculate distance between two points
 	h := math.Pow(math.Sin((lat2-lat1)/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin((long2-long1)/2), 2)
 
-	return 2 * r * math.Asin(math.Sqrt(h)), nil
+	return 2 * r * math.Asin(math.Sqrt(h))
 }
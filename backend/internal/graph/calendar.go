@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// ServiceCalendar answers which GTFS services are running on a given date,
+// built from calendar.txt and calendar_dates.txt
+type ServiceCalendar struct {
+	services   map[string]*gtfs.Calendar
+	exceptions map[string]map[string]gtfs.ExceptionType // serviceID -> YYYYMMDD -> exception type
+}
+
+// NewServiceCalendar creates a calendar from the parsed GTFS files, both may be empty
+func NewServiceCalendar(calendars []*gtfs.Calendar, calendarDates []*gtfs.CalendarDate) *ServiceCalendar {
+	sc := &ServiceCalendar{
+		services:   make(map[string]*gtfs.Calendar),
+		exceptions: make(map[string]map[string]gtfs.ExceptionType),
+	}
+	for _, c := range calendars {
+		sc.services[c.ServiceID] = c
+	}
+	for _, cd := range calendarDates {
+		if _, ok := sc.exceptions[cd.ServiceID]; !ok {
+			sc.exceptions[cd.ServiceID] = make(map[string]gtfs.ExceptionType)
+		}
+		sc.exceptions[cd.ServiceID][cd.Date.Format(gtfs.DATE_LAYOUT)] = cd.ExceptionType
+	}
+	return sc
+}
+
+// IsEmpty reports whether the feed had no calendar information at all
+func (sc *ServiceCalendar) IsEmpty() bool {
+	return sc == nil || (len(sc.services) == 0 && len(sc.exceptions) == 0)
+}
+
+// IsActive reports whether the service runs on the date. Without any calendar
+// information every service is considered active.
+func (sc *ServiceCalendar) IsActive(serviceID string, date time.Time) bool {
+	if sc.IsEmpty() {
+		return true
+	}
+	// calendar_dates.txt overrides calendar.txt
+	switch sc.exceptions[serviceID][date.Format(gtfs.DATE_LAYOUT)] {
+	case gtfs.EXCEPTION_ADDED:
+		return true
+	case gtfs.EXCEPTION_REMOVED:
+		return false
+	}
+	c, ok := sc.services[serviceID]
+	if !ok {
+		return false
+	}
+	// feed dates are midnight UTC
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if day.Before(c.StartDate.Time) || day.After(c.EndDate.Time) {
+		return false
+	}
+	switch date.Weekday() {
+	case time.Monday:
+		return bool(c.Monday)
+	case time.Tuesday:
+		return bool(c.Tuesday)
+	case time.Wednesday:
+		return bool(c.Wednesday)
+	case time.Thursday:
+		return bool(c.Thursday)
+	case time.Friday:
+		return bool(c.Friday)
+	case time.Saturday:
+		return bool(c.Saturday)
+	case time.Sunday:
+		return bool(c.Sunday)
+	}
+	return false
+}
+
+// ServicesOn returns the set of services running on the date, nil when the
+// feed has no calendar information and every service should be considered active
+func (sc *ServiceCalendar) ServicesOn(date time.Time) map[string]bool {
+	if sc.IsEmpty() {
+		return nil
+	}
+	active := make(map[string]bool)
+	for serviceID := range sc.services {
+		if sc.IsActive(serviceID, date) {
+			active[serviceID] = true
+		}
+	}
+	for serviceID := range sc.exceptions {
+		if sc.IsActive(serviceID, date) {
+			active[serviceID] = true
+		}
+	}
+	return active
+}
@@ -1,7 +1,9 @@
 package graph
 
 import (
+	"strconv"
 	"sync/atomic"
+	"time"
 )
 
 // ----------------------------
@@ -18,46 +20,87 @@ const (
 
 type EdgeProperties struct {
 	TripID         string   `json:"tripId"`
-	Departure      int      `json:"departure"` // minutes since midnight
-	Arrival        int      `json:"arrival"`
+	ServiceID      string   `json:"serviceId,omitempty"`
+	Departure      int      `json:"departure"` // seconds since start of service day, may exceed 24h
+	Arrival        int      `json:"arrival"`   // for walking edges the walking time in seconds
 	TransferType   EdgeType `json:"transferType"`
+	Mode           Mode     `json:"mode,omitempty"`
+	Distance       float64  `json:"distance,omitempty"`     // meters, for walking edges
+	Headway        int      `json:"headway,omitempty"`      // seconds, for frequency based trips whose times are estimates
+	StopSequence   int      `json:"stopSequence,omitempty"` // of the stop the trip departs from, tells apart the edges of a trip passing the same stops twice
 	SourceStopName string   `json:"sourceStopName"`
 	DestStopName   string   `json:"destStopName"`
+	// the line of the trip, shared by its edges and nil for walking edges,
+	// its fields are inlined in the JSON
+	*TripInfo
 }
 
 type Edge struct {
 	source   *Vertex
 	dest     *Vertex
+	key      string         // of the edge among the edges between its stops
 	Metadata EdgeProperties `json:"metadata"`
 }
 
+// TimedEdge is an edge of a found route together with the absolute times it's used at
+type TimedEdge struct {
+	*Edge
+	DepartureTime time.Time `json:"departureTime"`
+	ArrivalTime   time.Time `json:"arrivalTime"`
+}
+
 func NewEdge(from, to *Vertex, metadata EdgeProperties) *Edge {
 	return &Edge{source: from, dest: to, Metadata: metadata}
 }
+
+// AddEdge adds a directed edge between two vertices. Several edges may connect
+// the same pair of stops as long as they belong to different trips, an edge is
+// uniquely identified by (from, to, tripID). A trip passing the same pair of
+// stops again, like a circular line, adds another edge told apart by its stop
+// sequence, GetEdge returns the first one added.
 func (graph *SLGraph) AddEdge(from, to *Vertex, metadata EdgeProperties) (*Edge, error) {
 	if from == nil || to == nil {
 		return nil, ErrNilVertices
 	}
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+	key := metadata.TripID
+	// the first edge of the trip may have been removed, so the key with the
+	// stop sequence is checked either way
+	sequenceKey := key + "#" + strconv.Itoa(metadata.StopSequence)
+	if graph.edges[from.label][to.label][sequenceKey] != nil {
+		return nil, ErrEdgeAlreadyExists
+	}
+	if first := graph.edges[from.label][to.label][key]; first != nil {
+		if first.Metadata.StopSequence == metadata.StopSequence {
+			return nil, ErrEdgeAlreadyExists
+		}
+		key = sequenceKey
+	}
 
 	// add vertices if they don't exist
 	if _, ok := graph.vertices[from.label]; !ok {
-		graph.AddVertex(from)
+		graph.addVertex(from)
 	}
 	if _, ok := graph.vertices[to.label]; !ok {
-		graph.AddVertex(to)
+		graph.addVertex(to)
 	}
 
 	edge := NewEdge(from, to, metadata)
+	edge.key = key
 
 	// append to outgoing edges
 	from.AddEdge(edge)
 
-	// add to edge map
+	// add to edge index
 	if _, ok := graph.edges[from.label]; !ok {
-		graph.edges[from.label] = map[string]*Edge{to.label: edge}
-	} else {
-		graph.edges[from.label][to.label] = edge
+		graph.edges[from.label] = make(map[string]map[string]*Edge)
+	}
+	if _, ok := graph.edges[from.label][to.label]; !ok {
+		graph.edges[from.label][to.label] = make(map[string]*Edge)
 	}
+	graph.edges[from.label][to.label][key] = edge
+	graph.changed()
 
 	atomic.AddUint32(&graph.edgesCount, 1)
 	return edge, nil
@@ -69,14 +112,20 @@ func (e *Edge) Destination() *Stop {
 	return e.dest.metadata
 }
 
+// EdgesOf returns all incoming and outgoing edges of a vertex
 func (graph *SLGraph) EdgesOf(v *Vertex) []*Edge {
-	if v == nil || graph.vertices[v.label] == nil {
+	if v == nil {
+		return nil
+	}
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	if graph.vertices[v.label] == nil {
 		return nil
 	}
 	edges := make([]*Edge, 0)
 	// outgoing edges
-	if destMap, ok := graph.edges[v.label]; ok {
-		for _, edge := range destMap {
+	for _, tripMap := range graph.edges[v.label] {
+		for _, edge := range tripMap {
 			edges = append(edges, edge)
 		}
 	}
@@ -85,56 +134,110 @@ func (graph *SLGraph) EdgesOf(v *Vertex) []*Edge {
 		if srcLabel == v.label {
 			continue
 		}
-		if edge, ok := destMap[v.label]; ok {
+		for _, edge := range destMap[v.label] {
 			edges = append(edges, edge)
 		}
 	}
 	return edges
 }
 func (graph *SLGraph) AllEdges() []*Edge {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
 	var all []*Edge
 	for _, destMap := range graph.edges {
-		for _, edge := range destMap {
-			all = append(all, edge)
+		for _, tripMap := range destMap {
+			for _, edge := range tripMap {
+				all = append(all, edge)
+			}
 		}
 	}
 	return all
 }
+
+// EdgesBetween returns every edge (one per trip) going from one vertex to another
+func (graph *SLGraph) EdgesBetween(from, to *Vertex) []*Edge {
+	if from == nil || to == nil {
+		return nil
+	}
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	tripMap := graph.edges[from.label][to.label]
+	edges := make([]*Edge, 0, len(tripMap))
+	for _, edge := range tripMap {
+		edges = append(edges, edge)
+	}
+	return edges
+}
+
+// GetEdge returns the edge between two vertices for a given trip, walking edges have an empty tripID
+func (graph *SLGraph) GetEdge(from, to *Vertex, tripID string) *Edge {
+	if from == nil || to == nil {
+		return nil
+	}
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	return graph.edges[from.label][to.label][tripID]
+}
+
+// ContainsEdge reports whether there is at least one edge going from one vertex to another
 func (graph *SLGraph) ContainsEdge(from, to *Vertex) bool {
 	if from == nil || to == nil {
 		return false
 	}
-	if destMap, ok := graph.edges[from.label]; ok {
-		if _, ok2 := destMap[to.label]; ok2 {
-			return true
-		}
-	}
-	return false
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	return len(graph.edges[from.label][to.label]) > 0
+}
+
+// ContainsTripEdge reports whether the given trip has an edge going from one vertex to another
+func (graph *SLGraph) ContainsTripEdge(from, to *Vertex, tripID string) bool {
+	return graph.GetEdge(from, to, tripID) != nil
 }
 
 // ----------------------------
 // Remove methods
 // ----------------------------
 
+// RemoveEdge removes the edge between two vertices for a given trip
+func (graph *SLGraph) RemoveEdge(from, to *Vertex, tripID string) {
+	if from == nil || to == nil {
+		return
+	}
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+	graph.removeEdges(graph.edges[from.label][to.label][tripID])
+}
+
 func (graph *SLGraph) RemoveEdges(edges ...*Edge) {
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+	graph.removeEdges(edges...)
+}
+
+func (graph *SLGraph) removeEdges(edges ...*Edge) {
 	for _, e := range edges {
 		if e == nil || graph.vertices[e.source.label] == nil || graph.vertices[e.dest.label] == nil {
 			continue
 		}
-		delete(graph.edges[e.source.label], e.dest.label)
-		// remove from outgoing edges slice
-		src := graph.vertices[e.source.label]
-		for i, edge := range src.edges {
-			if edge.dest.label == e.dest.label {
-				src.edges = append(src.edges[:i], src.edges[i+1:]...)
-				break
-			}
+		tripMap := graph.edges[e.source.label][e.dest.label]
+		if tripMap[e.key] != e {
+			// edge is not part of the graph
+			continue
+		}
+		delete(tripMap, e.key)
+		if len(tripMap) == 0 {
+			delete(graph.edges[e.source.label], e.dest.label)
+		}
+		if len(graph.edges[e.source.label]) == 0 {
+			delete(graph.edges, e.source.label)
 		}
+		// remove from outgoing edges slice
+		e.source.edges = removeEdgeFromSlice(e.source.edges, e)
 		atomic.AddUint32(&graph.edgesCount, ^(uint32(1) - 1))
-		e.dest.inDegree--
+		e.dest.incoming = removeEdgeFromSlice(e.dest.incoming, e)
+		graph.changed()
 	}
 }
-
 func removeEdgeFromSlice(edges []*Edge, target *Edge) []*Edge {
 	for i, e := range edges {
 		if e == target {
@@ -0,0 +1,12 @@
+package graph
+
+import "time"
+
+// FindRouteMultiUnindexed is FindRouteMulti without the search index, for the
+// tests of this directory to check and benchmark the indexed search against
+func (graph *SLGraph) FindRouteMultiUnindexed(sources, targets []Endpoint, departure time.Time, profile RoutingProfile) *MultiRoute {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	ctx := graph.newSearchContext(departure, profile)
+	return graph.findRouteMultiUnindexed(ctx, sources, targets, departure)
+}
@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"log"
+
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// frequencyTripID is the trip ID of one run of a frequency based trip, runs
+// are separate vehicles so changing between them is a transfer
+func frequencyTripID(tripID string, start int) string {
+	return tripID + "@" + gtfs.Time(start).String()
+}
+
+// frequencyRuns returns the start times of the runs described by a row of
+// frequencies.txt, every headway from start_time while before end_time
+func frequencyRuns(f *gtfs.Frequency) []int {
+	if f.HeadwaySecs <= 0 || !f.StartTime.IsSet() || !f.EndTime.IsSet() {
+		return nil
+	}
+	var runs []int
+	for start := int(f.StartTime); start < int(f.EndTime); start += f.HeadwaySecs {
+		runs = append(runs, start)
+	}
+	return runs
+}
+
+// addFrequencyTripEdges expands a trip of frequencies.txt into one run per
+// headway. The stop times of the trip only give the travel times between the
+// stops, each run is shifted to start at its own departure.
+//
+// With exact_times=1 the runs leave exactly at the expanded times. With
+// exact_times=0 the feed only promises a vehicle every headway, so the runs are
+// the expected departures and their edges carry the headway, which searches
+// wait for when boarding them (see boardingWait).
+func (graph *SLGraph) addFrequencyTripEdges(template EdgeProperties, times []*gtfs.StopTime, frequencies []*gtfs.Frequency) error {
+	if len(times) == 0 {
+		return nil
+	}
+	if !times[0].DepartureTime.IsSet() {
+		log.Printf("Skipping frequency based trip %s, the first stop has no departure time", template.TripID)
+		return nil
+	}
+	first := int(times[0].DepartureTime)
+	for _, f := range frequencies {
+		runTemplate := template
+		if f.ExactTimes == gtfs.FREQUENCY_BASED {
+			runTemplate.Headway = f.HeadwaySecs
+		}
+		for _, start := range frequencyRuns(f) {
+			runTemplate.TripID = frequencyTripID(template.TripID, start)
+			if err := graph.addTripEdges(runTemplate, times, start-first); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+
+	pq "github.com/Durelius/next-week/internal/priority_queue"
+)
+
+// isochroneState is a stop reached on a trip, "" when walking or starting there
+type isochroneState struct {
+	vertex *Vertex
+	tripID string
+}
+
+// Reach is the earliest arrival at a stop found by Isochrone
+type Reach struct {
+	Stop    *Stop     `json:"stop"`
+	Arrival time.Time `json:"arrival"`
+	Seconds int       `json:"seconds"` // since the departure
+}
+
+// Isochrone finds the earliest arrival at every stop reachable from the place
+// within the budget when departing at the given time, soonest first. It
+// searches from the start to all stops at once, ordered by arrival. The
+// profile decides which edges may be used and how long changing vehicle
+// takes, but its penalties don't matter, only when a stop is reached does.
+//
+// A stop is searched from once for every trip it's reached on, staying on a
+// vehicle arriving later may still catch a departure that the first one to
+// arrive would need too long to change to.
+func (graph *SLGraph) Isochrone(from Place, departure time.Time, budget time.Duration, profile RoutingProfile) []Reach {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	ctx := graph.newSearchContext(departure, profile)
+	startTime := ctx.Seconds(departure)
+	limit := startTime + int(budget/time.Second)
+
+	open := make(pq.PriorityQueue, 0)
+	heap.Init(&open)
+	arrival := make(map[string]int)           // by stop and trip
+	states := make(map[string]isochroneState) // by stop and trip
+	reach := func(v *Vertex, tripID string, t int) {
+		key := v.label + "\x00" + tripID
+		if known, ok := arrival[key]; t > limit || ok && known <= t {
+			return
+		}
+		arrival[key] = t
+		states[key] = isochroneState{vertex: v, tripID: tripID}
+		heap.Push(&open, pq.NewItem(key, t, t))
+	}
+	for _, s := range graph.placeEndpoints(ctx, from) {
+		reach(s.Vertex, "", startTime+s.Seconds)
+	}
+	closed := make(map[string]bool)
+	found := make(map[string]bool) // stops already in reached
+	reached := []Reach{}
+	for len(open) > 0 {
+		current := heap.Pop(&open).(*pq.Item)
+		if closed[current.Value()] {
+			continue
+		}
+		closed[current.Value()] = true
+		state := states[current.Value()]
+		v := state.vertex
+		if !found[v.label] && v.metadata != nil {
+			found[v.label] = true
+			reached = append(reached, Reach{Stop: v.metadata, Arrival: ctx.Time(current.G()), Seconds: current.G() - startTime})
+		}
+
+		for _, edge := range v.edges {
+			run, _, ok := edge.calculateG(ctx, current.G(), state.tripID)
+			if !ok {
+				continue
+			}
+			reach(edge.dest, edge.Metadata.TripID, run.arrival)
+		}
+	}
+	// stops reached at the same time in the order of their IDs
+	sort.SliceStable(reached, func(i, j int) bool {
+		if reached[i].Seconds != reached[j].Seconds {
+			return reached[i].Seconds < reached[j].Seconds
+		}
+		return reached[i].Stop.StopID < reached[j].Stop.StopID
+	})
+	return reached
+}
@@ -0,0 +1,110 @@
+package graph
+
+import "time"
+
+// LegStop is a stop a leg passes between boarding and alighting
+type LegStop struct {
+	Stop      *Stop     `json:"stop"`
+	Arrival   time.Time `json:"arrival"`
+	Departure time.Time `json:"departure"`
+}
+
+// Leg is one ride with a single vehicle, or one walk between stops
+type Leg struct {
+	TransferType EdgeType `json:"transferType"`
+	TripID       string   `json:"tripId,omitempty"`
+	Mode         Mode     `json:"mode,omitempty"`
+	// the line of a ride, its fields are inlined in the JSON
+	*TripInfo
+	From              *Stop     `json:"from"` // boarding stop
+	To                *Stop     `json:"to"`   // alighting stop
+	Departure         time.Time `json:"departure"`
+	Arrival           time.Time `json:"arrival"`
+	IntermediateStops []LegStop `json:"intermediateStops"`
+	DurationSeconds   int       `json:"durationSeconds"`
+	Distance          float64   `json:"distance,omitempty"` // meters, for walks
+	Headway           int       `json:"headway,omitempty"`  // seconds, when the times are estimates
+	// the edges the leg was built from
+	Edges []*TimedEdge `json:"-"`
+}
+
+// Journey is a found route as the rider experiences it, one leg per ride or walk
+type Journey struct {
+	Legs            []*Leg    `json:"legs"`
+	Departure       time.Time `json:"departure"`
+	Arrival         time.Time `json:"arrival"`
+	DurationSeconds int       `json:"durationSeconds"`
+	Transfers       int       `json:"transfers"`
+	WalkSeconds     int       `json:"walkSeconds"`
+	WalkMeters      float64   `json:"walkMeters"`
+}
+
+// startsLeg reports whether the edge can't continue the leg
+func (l *Leg) startsLeg(e *TimedEdge) bool {
+	if e.Metadata.TransferType != l.TransferType {
+		return true
+	}
+	// consecutive walks are one walk
+	return l.TransferType == COMMUTE_EDGE && e.Metadata.TripID != l.TripID
+}
+
+func newLeg(e *TimedEdge) *Leg {
+	return &Leg{
+		TransferType:      e.Metadata.TransferType,
+		TripID:            e.Metadata.TripID,
+		Mode:              e.Metadata.Mode,
+		TripInfo:          e.Metadata.TripInfo,
+		From:              e.Source(),
+		Departure:         e.DepartureTime,
+		IntermediateStops: []LegStop{},
+		Headway:           e.Metadata.Headway,
+	}
+}
+
+func (l *Leg) add(e *TimedEdge) {
+	if len(l.Edges) > 0 {
+		previous := l.Edges[len(l.Edges)-1]
+		l.IntermediateStops = append(l.IntermediateStops, LegStop{
+			Stop:      previous.Destination(),
+			Arrival:   previous.ArrivalTime,
+			Departure: e.DepartureTime,
+		})
+	}
+	l.Edges = append(l.Edges, e)
+	l.To = e.Destination()
+	l.Arrival = e.ArrivalTime
+	l.DurationSeconds = int(l.Arrival.Sub(l.Departure).Seconds())
+	l.Distance += e.Metadata.Distance
+}
+
+// NewJourney collapses the edges of a found route into legs, consecutive
+// edges of the same trip become one ride. Returns nil for an empty route.
+func NewJourney(path []*TimedEdge) *Journey {
+	if len(path) == 0 {
+		return nil
+	}
+	journey := &Journey{Legs: []*Leg{}}
+	var leg *Leg
+	rides := 0
+	for _, e := range path {
+		if leg == nil || leg.startsLeg(e) {
+			leg = newLeg(e)
+			journey.Legs = append(journey.Legs, leg)
+			if leg.TransferType == COMMUTE_EDGE {
+				rides++
+			}
+		}
+		leg.add(e)
+	}
+	for _, leg := range journey.Legs {
+		if leg.TransferType == WALK_EDGE {
+			journey.WalkSeconds += leg.DurationSeconds
+			journey.WalkMeters += leg.Distance
+		}
+	}
+	journey.Departure = path[0].DepartureTime
+	journey.Arrival = path[len(path)-1].ArrivalTime
+	journey.DurationSeconds = int(journey.Arrival.Sub(journey.Departure).Seconds())
+	journey.Transfers = max(rides-1, 0)
+	return journey
+}
@@ -0,0 +1,193 @@
+package graph
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// labels of the temporary vertices used when routing from or to a coordinate,
+// GTFS stop IDs never start with #
+const (
+	ORIGIN_LABEL      = "#origin"
+	DESTINATION_LABEL = "#destination"
+)
+
+// Place is where a journey starts or ends, either a stop, a station or a
+// coordinate. A station may be left from and reached at any of its platforms.
+// Searches between stops or stations only find nothing from or to a coordinate.
+type Place struct {
+	Stop       *Vertex
+	Station    *Station
+	Lat, Lon   float64
+	coordinate bool
+}
+
+// StopPlace is a place at a stop of the graph
+func StopPlace(v *Vertex) Place {
+	return Place{Stop: v}
+}
+
+// StationPlace is a place at any of the platforms of a station
+func StationPlace(s *Station) Place {
+	return Place{Station: s}
+}
+
+// CoordinatePlace is a place at a coordinate, such as an address or a GPS position
+func CoordinatePlace(lat, lon float64) Place {
+	return Place{Lat: lat, Lon: lon, coordinate: true}
+}
+
+// IsCoordinate reports whether the place is a coordinate instead of a stop
+func (p Place) IsCoordinate() bool {
+	return p.coordinate
+}
+
+// IsStation reports whether the place is a station with several platforms to
+// choose from
+func (p Place) IsStation() bool {
+	return p.Station != nil && len(p.Station.platforms) > 1
+}
+
+// Vertex returns the stop of the place, the only platform of a station, or
+// nil for coordinates and stations with several platforms
+func (p Place) Vertex() *Vertex {
+	if p.Station != nil && len(p.Station.platforms) == 1 {
+		return p.Station.platforms[0]
+	}
+	return p.Stop
+}
+
+// newWalk creates a walking edge that isn't added to the graph, timed with the
+// profile of the search. The vertices are not modified.
+func newWalk(from, to *Vertex, distance float64, profile *RoutingProfile) *Edge {
+	return NewEdge(from, to, EdgeProperties{
+		Arrival:        profile.walkSeconds(distance),
+		TransferType:   WALK_EDGE,
+		Distance:       distance,
+		SourceStopName: from.metadata.StopName,
+		DestStopName:   to.metadata.StopName,
+	})
+}
+
+// newVirtualVertex creates a vertex for a coordinate, it's never added to the graph
+func newVirtualVertex(label, name string, lat, lon float64) *Vertex {
+	return &Vertex{
+		label: label,
+		metadata: &Stop{
+			StopID:        label,
+			StopName:      name,
+			StopNameLower: strings.ToLower(name),
+			StopLatitude:  strconv.FormatFloat(lat, 'f', -1, 64),
+			StopLongitude: strconv.FormatFloat(lon, 'f', -1, 64),
+			Lat:           lat,
+			Lon:           lon,
+			located:       true,
+		},
+	}
+}
+
+// FindRouteBetween is FindRoute between places, searching from every stop the
+// origin may start at to every stop the destination may be reached from. A
+// coordinate may use every stop within the walking distance of the profile,
+// and the walks to and from it become the first and last edges of the route,
+// from and to a virtual vertex that's never added to the graph. Two coordinates
+// close enough may also be walked between directly. A station may use any of
+// its platforms. Returns nil if a stop place is missing or no stop is within
+// walking distance.
+func (graph *SLGraph) FindRouteBetween(from, to Place, departure time.Time, profile RoutingProfile) []*TimedEdge {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	ctx := graph.newSearchContext(departure, profile)
+
+	sources := graph.placeEndpoints(ctx, from)
+	targets := graph.placeEndpoints(ctx, to)
+	route := graph.findRouteMulti(ctx, sources, targets, departure)
+	start := ctx.Time(ctx.Seconds(departure)) // in the timezone of the search
+
+	var origin, destination *Vertex
+	if from.IsCoordinate() {
+		origin = newVirtualVertex(ORIGIN_LABEL, "Origin", from.Lat, from.Lon)
+	}
+	if to.IsCoordinate() {
+		destination = newVirtualVertex(DESTINATION_LABEL, "Destination", to.Lat, to.Lon)
+	}
+	if origin != nil && destination != nil {
+		dist := DistanceMeters(from.Lat, from.Lon, to.Lat, to.Lon)
+		if dist <= profile.MaxWalkDistance {
+			walk := profile.walkSeconds(dist)
+			if route == nil || walk+profile.WalkPenalty <= route.Cost {
+				return []*TimedEdge{timedWalk(newWalk(origin, destination, dist, ctx.profile), start, walk)}
+			}
+		}
+	}
+	if route == nil {
+		return nil
+	}
+	path := route.Path
+	if origin != nil {
+		source := route.Source
+		walk := timedWalk(newWalk(origin, source.Vertex, DistanceMeters(from.Lat, from.Lon, source.Vertex.metadata.Lat, source.Vertex.metadata.Lon), ctx.profile), start, source.Seconds)
+		path = append([]*TimedEdge{walk}, path...)
+	}
+	if destination != nil {
+		target := route.Target
+		arrival := start.Add(time.Duration(route.Source.Seconds) * time.Second)
+		if len(path) > 0 {
+			arrival = path[len(path)-1].ArrivalTime
+		}
+		walk := timedWalk(newWalk(target.Vertex, destination, DistanceMeters(target.Vertex.metadata.Lat, target.Vertex.metadata.Lon, to.Lat, to.Lon), ctx.profile), arrival, target.Seconds)
+		path = append(path, walk)
+	}
+	if len(path) == 0 {
+		return nil
+	}
+	return path
+}
+
+// placeEndpoints returns the stops a search may start or end at for the place:
+// the stop itself, the platforms of a station, or the stops within walking
+// distance of a coordinate
+func (graph *SLGraph) placeEndpoints(ctx *searchContext, p Place) []Endpoint {
+	var endpoints []Endpoint
+	switch {
+	case p.IsCoordinate():
+		for _, near := range graph.stopIndex.Within(p.Lat, p.Lon, ctx.profile.MaxWalkDistance) {
+			endpoints = append(endpoints, Endpoint{
+				Vertex:  near.Vertex,
+				Seconds: ctx.profile.walkSeconds(near.Distance),
+				Penalty: ctx.profile.WalkPenalty,
+			})
+		}
+	case p.Station != nil:
+		for _, platform := range p.Station.platforms {
+			endpoints = append(endpoints, Endpoint{Vertex: platform})
+		}
+	case p.Stop != nil:
+		endpoints = append(endpoints, Endpoint{Vertex: p.Stop})
+	}
+	return endpoints
+}
+
+// placeVertices returns the stops of a place that are used without walking: the
+// stop itself or the platforms of a station, none for a coordinate
+func placeVertices(p Place) []*Vertex {
+	switch {
+	case p.IsCoordinate():
+		return nil
+	case p.Station != nil:
+		return p.Station.platforms
+	case p.Stop != nil:
+		return []*Vertex{p.Stop}
+	}
+	return nil
+}
+
+// timedWalk is a walk starting at departure taking the seconds
+func timedWalk(e *Edge, departure time.Time, seconds int) *TimedEdge {
+	return &TimedEdge{
+		Edge:          e,
+		DepartureTime: departure,
+		ArrivalTime:   departure.Add(time.Duration(seconds) * time.Second),
+	}
+}
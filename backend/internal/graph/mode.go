@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// Mode is the kind of vehicle a trip is run with, derived from the GTFS route_type
+type Mode int
+
+// MODES
+const (
+	MODE_UNKNOWN Mode = iota
+	MODE_TRAM
+	MODE_METRO
+	MODE_RAIL
+	MODE_BUS
+	MODE_FERRY
+)
+
+var modeNames = map[Mode]string{
+	MODE_UNKNOWN: "unknown",
+	MODE_TRAM:    "tram",
+	MODE_METRO:   "metro",
+	MODE_RAIL:    "rail",
+	MODE_BUS:     "bus",
+	MODE_FERRY:   "ferry",
+}
+
+// ModeFromRouteType maps both basic (0-12) and extended (100-1700) GTFS route types to a mode
+func ModeFromRouteType(routeType gtfs.RouteType) Mode {
+	switch routeType.Basic() {
+	case gtfs.ROUTE_TYPE_TRAM, gtfs.ROUTE_TYPE_CABLE_TRAM, gtfs.ROUTE_TYPE_MONORAIL:
+		return MODE_TRAM
+	case gtfs.ROUTE_TYPE_SUBWAY:
+		return MODE_METRO
+	case gtfs.ROUTE_TYPE_RAIL:
+		return MODE_RAIL
+	case gtfs.ROUTE_TYPE_BUS, gtfs.ROUTE_TYPE_TROLLEYBUS:
+		return MODE_BUS
+	case gtfs.ROUTE_TYPE_FERRY:
+		return MODE_FERRY
+	}
+	return MODE_UNKNOWN
+}
+
+// ParseMode parses the name of a mode, as returned by String
+func ParseMode(name string) (Mode, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for mode, modeName := range modeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return MODE_UNKNOWN, fmt.Errorf("unknown mode %q", name)
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return modeNames[MODE_UNKNOWN]
+}
+
+func (m Mode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Mode) UnmarshalText(text []byte) error {
+	mode, err := ParseMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
@@ -0,0 +1,302 @@
+package graph
+
+import (
+	"container/heap"
+	"slices"
+	"time"
+)
+
+const (
+	// paretoMaxBoardings limits the number of vehicles a journey may use
+	paretoMaxBoardings = 5
+	// paretoSlack is how much later than the earliest arrival an alternative may arrive
+	paretoSlack = 60 * 60
+)
+
+// RouteOption is one Pareto-optimal journey, no other option is at least as
+// good in arrival time, number of transfers and walking time while being
+// better in one of them
+type RouteOption struct {
+	Edges       []*TimedEdge `json:"edges"`
+	Departure   time.Time    `json:"departure"`
+	Arrival     time.Time    `json:"arrival"`
+	Transfers   int          `json:"transfers"`
+	WalkSeconds int          `json:"walkSeconds"`
+}
+
+// label is a partial journey ending at a vertex during the multi-criteria search
+type label struct {
+	vertex    *Vertex
+	time      int // arrival at vertex in search seconds
+	boardings int // number of vehicles used
+	walk      int // seconds spent walking
+	tripID    string
+	hop       hop
+	prev      *label
+	f         int
+	index     int
+	removed   bool // dominated by a later label
+}
+
+// dominates reports whether a is at least as good as b in every criterion.
+// Labels on different trips can only be compared if a is still as good after
+// paying for the extra boarding b doesn't need.
+func (a *label) dominates(b *label) bool {
+	if a.time > b.time || a.walk > b.walk {
+		return false
+	}
+	boardings := a.boardings
+	if b.tripID != "" && a.tripID != b.tripID {
+		boardings++
+	}
+	return boardings <= b.boardings
+}
+
+// improves reports whether the label is not dominated by any label in the bag
+func (l *label) improves(bag []*label) bool {
+	for _, other := range bag {
+		if other.dominates(l) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *label) transfers() int {
+	return max(l.boardings-1, 0)
+}
+
+// labelQueue is a priority queue of labels ordered by f
+type labelQueue []*label
+
+func (q labelQueue) Len() int { return len(q) }
+func (q labelQueue) Less(i, j int) bool {
+	if q[i].f != q[j].f {
+		return q[i].f < q[j].f
+	}
+	return q[i].boardings < q[j].boardings
+}
+func (q labelQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *labelQueue) Push(x any) {
+	l := x.(*label)
+	l.index = len(*q)
+	*q = append(*q, l)
+}
+func (q *labelQueue) Pop() any {
+	old := *q
+	n := len(old)
+	l := old[n-1]
+	old[n-1] = nil
+	l.index = -1
+	*q = old[:n-1]
+	return l
+}
+
+// FindParetoRoutes returns the Pareto-optimal journeys between two stops
+// departing at the given time, trading off arrival time, number of transfers
+// and walking time. Options are sorted by arrival. It is a multi-criteria
+// label-setting variant of FindRoute where every stop keeps a bag of
+// non-dominated labels instead of a single best arrival.
+func (graph *SLGraph) FindParetoRoutes(start *Vertex, destination *Vertex, departure time.Time, profile RoutingProfile) []*RouteOption {
+	return graph.FindParetoRoutesBetween(StopPlace(start), StopPlace(destination), departure, profile)
+}
+
+// FindParetoRoutesBetween is FindParetoRoutes between stops or stations (see Place)
+func (graph *SLGraph) FindParetoRoutesBetween(from, to Place, departure time.Time, profile RoutingProfile) []*RouteOption {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	return graph.findParetoRoutes(placeVertices(from), placeVertices(to), departure, profile)
+}
+
+func (graph *SLGraph) findParetoRoutes(starts, destinations []*Vertex, departure time.Time, profile RoutingProfile) []*RouteOption {
+	if len(starts) == 0 || len(destinations) == 0 {
+		return nil
+	}
+	ctx := graph.newSearchContext(departure, profile)
+	options := newParetoSearch(ctx, destinations).run(starts, ctx.Seconds(departure))
+	slices.SortStableFunc(options, func(a, b *RouteOption) int {
+		return a.Arrival.Compare(b.Arrival)
+	})
+	return options
+}
+
+// paretoSearch is the bags of a Pareto search. A range search runs it for
+// every departure latest first, so the labels of later departures prune the
+// ones of earlier departures that get nowhere sooner.
+type paretoSearch struct {
+	ctx       *searchContext
+	targets   map[string]Endpoint
+	estimate  func(v *Vertex) int
+	bags      map[string][]*label
+	targetBag []*label
+}
+
+func newParetoSearch(ctx *searchContext, destinations []*Vertex) *paretoSearch {
+	targets := make(map[string]Endpoint, len(destinations))
+	for _, v := range destinations {
+		targets[v.label] = Endpoint{Vertex: v}
+	}
+	return &paretoSearch{
+		ctx:      ctx,
+		targets:  targets,
+		estimate: estimateTo(targets),
+		bags:     make(map[string][]*label),
+	}
+}
+
+// add keeps the label in the bag of its vertex unless it's dominated,
+// removing the labels it dominates
+func (s *paretoSearch) add(next *label) bool {
+	bag := s.bags[next.vertex.label]
+	if !next.improves(bag) {
+		return false
+	}
+	kept := bag[:0]
+	for _, other := range bag {
+		if next.dominates(other) {
+			other.removed = true
+			continue
+		}
+		kept = append(kept, other)
+	}
+	s.bags[next.vertex.label] = append(kept, next)
+	return true
+}
+
+// run searches from the starts departing at startTime in search seconds,
+// returning the options found that no other label of the run dominates
+func (s *paretoSearch) run(starts []*Vertex, startTime int) []*RouteOption {
+	var found []*label
+	horizon := -1
+
+	open := make(labelQueue, 0)
+	heap.Init(&open)
+	for _, start := range starts {
+		first := &label{vertex: start, time: startTime, f: startTime}
+		if s.add(first) {
+			heap.Push(&open, first)
+		}
+	}
+	for len(open) > 0 {
+		current := heap.Pop(&open).(*label)
+		if current.removed {
+			continue
+		}
+		if horizon != -1 && current.time > horizon {
+			break
+		}
+		if _, ok := s.targets[current.vertex.label]; ok {
+			if horizon == -1 {
+				horizon = current.time + paretoSlack
+			}
+			s.targetBag = append(s.targetBag, current)
+			found = append(found, current)
+			continue
+		}
+		for _, edge := range current.vertex.edges {
+			next, ok := current.extend(s.ctx, edge)
+			if !ok {
+				continue
+			}
+			if _, ok := s.targets[next.vertex.label]; ok {
+				// the journey ends here, so the trip no longer matters for dominance
+				next.tripID = ""
+			}
+			// target pruning, the label can only get worse from here
+			if !next.improvesTarget(s.targetBag) || !s.add(next) {
+				continue
+			}
+			next.f = next.time + s.estimate(next.vertex)
+			heap.Push(&open, next)
+		}
+	}
+
+	options := make([]*RouteOption, 0, len(found))
+	for _, l := range found {
+		// the heuristic isn't exact, so a label found later may still dominate
+		if !l.removed {
+			options = append(options, l.option(s.ctx))
+		}
+	}
+	return options
+}
+
+// extend follows an edge from the label, returning the label at its end.
+// Penalties of the profile aren't used since walking and transfers are criteria.
+func (l *label) extend(ctx *searchContext, edge *Edge) (*label, bool) {
+	if !edge.usable(ctx.profile) {
+		return nil, false
+	}
+	earliest := l.time
+	if l.tripID != edge.Metadata.TripID {
+		earliest += edge.boardingWait()
+	}
+	if edge.changesTrip(l.tripID) {
+		change, ok := ctx.minChangeTime(edge.source.label)
+		if !ok {
+			return nil, false
+		}
+		earliest += change
+	}
+	departure, arrival, ok := edge.nextDeparture(ctx, earliest)
+	if !ok {
+		return nil, false
+	}
+	next := &label{
+		vertex:    edge.dest,
+		time:      arrival,
+		boardings: l.boardings,
+		walk:      l.walk,
+		tripID:    edge.Metadata.TripID,
+		hop:       hop{edge: edge, departure: departure, arrival: arrival},
+		prev:      l,
+	}
+	if edge.Metadata.TransferType == WALK_EDGE {
+		next.walk += arrival - departure
+		return next, true
+	}
+	if l.tripID != edge.Metadata.TripID {
+		next.boardings++
+		if next.boardings > paretoMaxBoardings {
+			return nil, false
+		}
+	}
+	return next, true
+}
+
+// improvesTarget reports whether the label could still lead to a journey not
+// dominated by the ones already found
+func (l *label) improvesTarget(targetBag []*label) bool {
+	for _, t := range targetBag {
+		if t.time <= l.time && t.boardings <= l.boardings && t.walk <= l.walk {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *label) option(ctx *searchContext) *RouteOption {
+	var path []*TimedEdge
+	for current := l; current.prev != nil; current = current.prev {
+		path = append(path, &TimedEdge{
+			Edge:          current.hop.edge,
+			DepartureTime: ctx.Time(current.hop.departure),
+			ArrivalTime:   ctx.Time(current.hop.arrival),
+		})
+	}
+	slices.Reverse(path)
+	option := &RouteOption{
+		Edges:       path,
+		Arrival:     ctx.Time(l.time),
+		Transfers:   l.transfers(),
+		WalkSeconds: l.walk,
+	}
+	if len(path) > 0 {
+		option.Departure = path[0].DepartureTime
+	}
+	return option
+}
@@ -0,0 +1,76 @@
+package graph
+
+// MAX_WALK_DISTANCE is the longest walking edge generated between stops in
+// meters, a routing profile can only walk shorter distances
+const MAX_WALK_DISTANCE = 1000.0
+
+// RoutingProfile holds the costs used by route searches, so that walking and
+// changing vehicles can be weighed differently per traveler
+type RoutingProfile struct {
+	WalkSpeed       float64      // meters per minute
+	MaxWalkDistance float64      // meters, longer walking edges are not used
+	WalkPenalty     int          // seconds added to the cost of every walking edge
+	TransferPenalty int          // seconds added to the cost of changing vehicle
+	MinTransferTime int          // seconds needed between arriving and departing on another trip, unless the feed says otherwise
+	ModePenalties   map[Mode]int // seconds added to the cost of boarding a vehicle of the mode
+	ExcludedModes   map[Mode]bool
+}
+
+// DefaultProfile returns the profile used when nothing else is asked for
+func DefaultProfile() RoutingProfile {
+	return RoutingProfile{
+		WalkSpeed:       80,
+		MaxWalkDistance: 400,
+		WalkPenalty:     5 * 60,
+		TransferPenalty: 5 * 60,
+		MinTransferTime: 2 * 60,
+	}
+}
+
+// ReducedMobilityProfile walks slowly and as little as possible, and takes its time changing vehicle
+func ReducedMobilityProfile() RoutingProfile {
+	return RoutingProfile{
+		WalkSpeed:       40,
+		MaxWalkDistance: 200,
+		WalkPenalty:     10 * 60,
+		TransferPenalty: 10 * 60,
+		MinTransferTime: 5 * 60,
+	}
+}
+
+// LuggageProfile avoids walking and changing vehicle
+func LuggageProfile() RoutingProfile {
+	return RoutingProfile{
+		WalkSpeed:       60,
+		MaxWalkDistance: 300,
+		WalkPenalty:     10 * 60,
+		TransferPenalty: 10 * 60,
+		MinTransferTime: 3 * 60,
+	}
+}
+
+// ProfileByName returns a predefined profile, "default", "reducedMobility" or "luggage"
+func ProfileByName(name string) (RoutingProfile, bool) {
+	switch name {
+	case "", "default":
+		return DefaultProfile(), true
+	case "reducedMobility":
+		return ReducedMobilityProfile(), true
+	case "luggage":
+		return LuggageProfile(), true
+	}
+	return RoutingProfile{}, false
+}
+
+func (p *RoutingProfile) walkSeconds(meters float64) int {
+	speed := p.WalkSpeed
+	if speed <= 0 {
+		speed = DefaultProfile().WalkSpeed
+	}
+	seconds := int(meters / speed * 60)
+	//minimum of 1 minute
+	if seconds < 60 {
+		seconds = 60
+	}
+	return seconds
+}
@@ -0,0 +1,111 @@
+package graph
+
+import (
+	"slices"
+	"time"
+)
+
+// FindRoutesInWindow returns every non-dominated journey between two stops
+// departing within [from, until]. A journey dominates another if it leaves no
+// earlier, arrives no later and has no more transfers and walking.
+//
+// It is a range search like rRAPTOR: the Pareto search is run for every time
+// a vehicle can be boarded from the start, either directly or after walking to
+// a nearby stop, latest first. The labels of later departures are kept, so an
+// earlier departure only searches where it gets sooner, with fewer transfers
+// or less walking.
+func (graph *SLGraph) FindRoutesInWindow(start *Vertex, destination *Vertex, from, until time.Time, profile RoutingProfile) []*RouteOption {
+	return graph.FindRoutesInWindowBetween(StopPlace(start), StopPlace(destination), from, until, profile)
+}
+
+// FindRoutesInWindowBetween is FindRoutesInWindow between stops or stations (see Place)
+func (graph *SLGraph) FindRoutesInWindowBetween(origin, destination Place, from, until time.Time, profile RoutingProfile) []*RouteOption {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	ctx := graph.newSearchContext(from, profile)
+	windowStart, windowEnd := ctx.Seconds(from), ctx.Seconds(until)
+	starts, destinations := placeVertices(origin), placeVertices(destination)
+
+	var departures []int
+	for _, start := range starts {
+		departures = append(departures, boardingTimes(ctx, start, windowStart, windowEnd)...)
+	}
+	slices.Sort(departures)
+	departures = slices.Compact(departures)
+
+	if len(starts) == 0 || len(destinations) == 0 {
+		return nil
+	}
+	search := newParetoSearch(ctx, destinations)
+	var candidates []*RouteOption
+	for i := len(departures) - 1; i >= 0; i-- {
+		for _, option := range search.run(starts, departures[i]) {
+			if option.Departure.Before(from) || option.Departure.After(until) {
+				continue
+			}
+			candidates = append(candidates, option)
+		}
+	}
+
+	var options []*RouteOption
+	for i, option := range candidates {
+		dominated := false
+		for j, other := range candidates {
+			if i == j || !other.dominates(option) {
+				continue
+			}
+			// of two equal options keep the first one
+			if !option.dominates(other) || j < i {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			options = append(options, option)
+		}
+	}
+	slices.SortStableFunc(options, func(a, b *RouteOption) int {
+		if c := a.Departure.Compare(b.Departure); c != 0 {
+			return c
+		}
+		return a.Arrival.Compare(b.Arrival)
+	})
+	return options
+}
+
+// boardingTimes returns the times within [from, to] to leave the stop at to
+// board a vehicle, either there or after walking to a nearby stop
+func boardingTimes(ctx *searchContext, start *Vertex, from, to int) []int {
+	var departures []int
+	for _, edge := range start.edges {
+		if !edge.usable(ctx.profile) {
+			continue
+		}
+		if edge.Metadata.TransferType == WALK_EDGE {
+			// leave early enough to catch departures from the stop walked to
+			walk, ok := ctx.walkTime(edge)
+			if !ok {
+				continue
+			}
+			for _, next := range edge.dest.edges {
+				for _, departure := range next.departuresBetween(ctx, from+walk, to+walk) {
+					departures = append(departures, departure-next.boardingWait()-walk)
+				}
+			}
+			continue
+		}
+		for _, departure := range edge.departuresBetween(ctx, from, to) {
+			departures = append(departures, departure-edge.boardingWait())
+		}
+	}
+	return departures
+}
+
+// dominates reports whether o is at least as good as other in departure,
+// arrival, transfers and walking time
+func (o *RouteOption) dominates(other *RouteOption) bool {
+	return !o.Departure.Before(other.Departure) &&
+		!o.Arrival.After(other.Arrival) &&
+		o.Transfers <= other.Transfers &&
+		o.WalkSeconds <= other.WalkSeconds
+}
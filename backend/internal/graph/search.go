@@ -4,85 +4,288 @@ import (
 	"container/heap"
 	"log"
 	"slices"
-	"sort"
-	"strings"
+	"time"
 
 	pq "github.com/Durelius/next-week/internal/priority_queue"
 )
 
-// FindRoute finds the fastest way between two stops using a custom implementation of the A* algorithm
-func (graph *SLGraph) FindRoute(start *Vertex, destination *Vertex, startTime int) []*Edge {
+// MAX_HEURISTIC_TARGETS is the most targets the search estimates the remaining
+// cost to, with more it searches without the estimate instead of measuring the
+// distance to every target at every step
+const MAX_HEURISTIC_TARGETS = 64
+
+// Endpoint is a vertex a search may start or end at. Seconds is the travel time
+// between the vertex and where the journey starts or ends, like the walk from
+// a coordinate to a stop, and Penalty is added to the cost of using it.
+type Endpoint struct {
+	Vertex  *Vertex
+	Seconds int
+	Penalty int
+}
+
+// MultiRoute is the cheapest route between any of the sources and any of the
+// targets of a search
+type MultiRoute struct {
+	Path   []*TimedEdge // empty if the source is a target
+	Source Endpoint
+	Target Endpoint
+	Cost   int // seconds including the endpoints and the profile's penalties
+}
+
+// FindRoute finds the cheapest way between two stops departing at the given time
+// using a custom implementation of the A* algorithm. The cost is the travel time
+// plus the penalties of the routing profile. Only trips whose service runs on the
+// day they're used are considered, and the search may continue past midnight
+// onto the next service day.
+func (graph *SLGraph) FindRoute(start *Vertex, destination *Vertex, departure time.Time, profile RoutingProfile) []*TimedEdge {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	ctx := graph.newSearchContext(departure, profile)
+	return graph.findRoute(ctx, start, destination, departure)
+}
+
+func (graph *SLGraph) findRoute(ctx *searchContext, start *Vertex, destination *Vertex, departure time.Time) []*TimedEdge {
+	route := graph.findRouteMulti(ctx, []Endpoint{{Vertex: start}}, []Endpoint{{Vertex: destination}}, departure)
+	if route == nil {
+		return nil
+	}
+	return route.Path
+}
+
+// FindRouteMulti is FindRoute from any of the sources to any of the targets. The
+// search starts at every source at once, each reached Seconds after the
+// departure, and ends at the target cheapest to reach including its Seconds.
+// Returns nil if no target can be reached.
+func (graph *SLGraph) FindRouteMulti(sources, targets []Endpoint, departure time.Time, profile RoutingProfile) *MultiRoute {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	ctx := graph.newSearchContext(departure, profile)
+	return graph.findRouteMulti(ctx, sources, targets, departure)
+}
+
+// cheapestEndpoints keeps the cheapest endpoint of each vertex
+func cheapestEndpoints(endpoints []Endpoint) map[string]Endpoint {
+	cheapest := make(map[string]Endpoint, len(endpoints))
+	for _, e := range endpoints {
+		if e.Vertex == nil {
+			continue
+		}
+		if other, ok := cheapest[e.Vertex.label]; !ok || e.Seconds+e.Penalty < other.Seconds+other.Penalty {
+			cheapest[e.Vertex.label] = e
+		}
+	}
+	return cheapest
+}
+
+// estimateTo returns the least estimated cost from a vertex to the
+// destination through any of the targets
+func estimateTo(targets map[string]Endpoint) func(v *Vertex) int {
+	return func(v *Vertex) int {
+		if len(targets) > MAX_HEURISTIC_TARGETS {
+			return 0
+		}
+		h := -1
+		for _, t := range targets {
+			if cost := calculateH(v.metadata, t.Vertex.metadata) + t.Seconds + t.Penalty; h < 0 || cost < h {
+				h = cost
+			}
+		}
+		return h
+	}
+}
+
+func (graph *SLGraph) findRouteMulti(ctx *searchContext, sources, targets []Endpoint, departure time.Time) *MultiRoute {
+	idx := graph.searchIndex()
+	startTime := ctx.Seconds(departure)
+	// endpoints not in the graph can't be reached
+	indexed := func(endpoints []Endpoint) map[int32]Endpoint {
+		byID := make(map[int32]Endpoint, len(endpoints))
+		for label, e := range cheapestEndpoints(endpoints) {
+			if id, ok := idx.ids[label]; ok && idx.vertices[id] == e.Vertex {
+				byID[id] = e
+			}
+		}
+		return byID
+	}
+	sourceOf, targetOf := indexed(sources), indexed(targets)
+	if len(sourceOf) == 0 || len(targetOf) == 0 {
+		return nil
+	}
+	cheapestTargets := make(map[string]Endpoint, len(targetOf))
+	for _, t := range targetOf {
+		cheapestTargets[t.Vertex.label] = t
+	}
+	estimate := estimateTo(cheapestTargets)
+	penalties := newRidePenalties(ctx.profile)
+
+	st := idx.acquire()
+	defer idx.release(st)
+	for id, s := range sourceOf {
+		g := s.Seconds + s.Penalty
+		if st.reach(id, g, hop{}, id, noTrip) {
+			st.open.push(openItem{f: g + estimate(s.Vertex), g: g, id: id})
+		}
+	}
+	var best *MultiRoute
+	for len(st.open) > 0 {
+		current := st.open.pop()
+		if best != nil && current.f >= best.Cost {
+			break
+		}
+		id := current.id
+		if st.closed[id] == st.generation {
+			continue
+		}
+		st.closed[id] = st.generation
+
+		currentTrip := st.trip[id]
+		currentTime := startTime + sourceOf[id].Seconds
+		if st.came[id].edge != nil {
+			currentTime = st.came[id].arrival
+		}
+		if target, ok := targetOf[id]; ok {
+			if cost := current.g + target.Seconds + target.Penalty; best == nil || cost < best.Cost {
+				path, source := idx.pathTo(ctx, st, id, sourceOf)
+				best = &MultiRoute{Path: path, Source: source, Target: target, Cost: cost}
+			}
+		}
+
+		for _, w := range idx.walks[idx.firstWalk[id]:idx.firstWalk[id+1]] {
+			run, cost, ok := w.edge.calculateG(ctx, currentTime, "")
+			if ok && st.reach(w.dest, current.g+cost, run, id, noTrip) {
+				st.open.push(openItem{f: current.g + cost + estimate(w.edge.dest), g: current.g + cost, id: w.dest})
+			}
+		}
+		change, canChange := 0, true
+		if currentTrip != noTrip {
+			change, canChange = ctx.minChangeTime(idx.vertices[id].label)
+		}
+		connections := idx.connections[idx.firstConnection[id]:idx.firstConnection[id+1]]
+		for i := range connections {
+			c := &connections[i]
+			run, trip, cost, ok := idx.nextRide(ctx, c, currentTime, currentTrip, change, canChange, penalties)
+			if ok && st.reach(c.dest, current.g+cost, run, id, trip) {
+				st.open.push(openItem{f: current.g + cost + estimate(run.edge.dest), g: current.g + cost, id: c.dest})
+			}
+		}
+	}
+	return best
+}
+
+// pathTo follows the edges used to reach a vertex back to the source it was
+// reached from
+func (idx *searchIndex) pathTo(ctx *searchContext, st *searchState, id int32, sourceOf map[int32]Endpoint) ([]*TimedEdge, Endpoint) {
+	var path []*TimedEdge
+	for st.came[id].edge != nil {
+		prev := st.came[id]
+		path = append(path, &TimedEdge{
+			Edge:          prev.edge,
+			DepartureTime: ctx.Time(prev.departure),
+			ArrivalTime:   ctx.Time(prev.arrival),
+		})
+		id = st.from[id] // go backwards
+	}
+	slices.Reverse(path)
+	return path, sourceOf[id]
+}
+
+// findRouteMultiUnindexed is findRouteMulti searching the vertex maps and edge
+// lists of the graph directly instead of the search index. It's slower, and
+// kept to check and benchmark the indexed search against.
+func (graph *SLGraph) findRouteMultiUnindexed(ctx *searchContext, sources, targets []Endpoint, departure time.Time) *MultiRoute {
+	startTime := ctx.Seconds(departure)
+	sourceOf := cheapestEndpoints(sources)
+	targetOf := cheapestEndpoints(targets)
+	if len(sourceOf) == 0 || len(targetOf) == 0 {
+		return nil
+	}
+	estimate := estimateTo(targetOf)
+
 	open := make(pq.PriorityQueue, 0)
 	heap.Init(&open)
-	heap.Push(&open, pq.NewItem(start.metadata.StopID, startTime, startTime))
 	closed := make(map[string]bool)
 	bestG := make(map[string]int)
-	bestG[start.label] = startTime
-	cameFrom := make(map[string]*Edge) //"To reach this stop, we used this edge"
+	cameFrom := make(map[string]hop) //"To reach this stop, we used this edge"
+	for label, s := range sourceOf {
+		g := s.Seconds + s.Penalty
+		bestG[label] = g
+		heap.Push(&open, pq.NewItem(label, g, g+estimate(s.Vertex)))
+	}
+	var best *MultiRoute
 	for len(open) > 0 {
 		current := heap.Pop(&open).(*pq.Item)
-		currentStop := graph.GetVertexByID(current.Value())
+		if best != nil && current.F() >= best.Cost {
+			break
+		}
+		if closed[current.Value()] {
+			continue
+		}
+		closed[current.Value()] = true
+		currentStop := graph.vertices[current.Value()]
 
 		currentTripID := ""
-		if prevEdge, ok := cameFrom[current.Value()]; ok {
-			currentTripID = prevEdge.Metadata.TripID
+		currentTime := startTime + sourceOf[current.Value()].Seconds
+		if prev, ok := cameFrom[current.Value()]; ok {
+			currentTripID = prev.edge.Metadata.TripID
+			currentTime = prev.arrival
 		}
-		if current.Value() == destination.label {
-
-			var path []*Edge
-			currentID := destination.label
-
-			//find starting point
-			for currentID != start.label {
-				edge, ok := cameFrom[currentID]
+		if target, ok := targetOf[current.Value()]; ok {
+			if cost := current.G() + target.Seconds + target.Penalty; best == nil || cost < best.Cost {
+				path, source, ok := graph.pathTo(ctx, current.Value(), cameFrom, sourceOf)
 				if !ok {
-					log.Println("error: Didn't find came from")
 					return nil
 				}
-
-				path = append(path, edge)
-				currentID = edge.source.label // go backwards
+				best = &MultiRoute{Path: path, Source: source, Target: target, Cost: cost}
 			}
-
-			slices.Reverse(path)
-			return path
 		}
 
-		if closed[current.Value()] {
-			continue
-		}
-
-		closed[current.Value()] = true
 		for _, edge := range currentStop.edges {
 			neighborID := edge.dest.label
-			newG := edge.calculateG(current.G(), currentTripID)
-			if newG == -1 {
+			run, cost, ok := edge.calculateG(ctx, currentTime, currentTripID)
+			if !ok {
 				continue
 			}
-			if best, exists := bestG[neighborID]; !exists || newG < best {
+			newG := current.G() + cost
+			if known, exists := bestG[neighborID]; !exists || newG < known {
 				bestG[neighborID] = newG
-				neighborStop := graph.GetVertexByID(neighborID)
-				h := calculateH(neighborStop.metadata, destination.metadata)
-				f := newG + h
+				f := newG + estimate(edge.dest)
 				heap.Push(&open, pq.NewItem(neighborID, newG, f))
-				cameFrom[neighborID] = edge
+				cameFrom[neighborID] = run
 			}
 		}
 
 	}
-	return nil
+	return best
 }
 
-func (graph *SLGraph) FindStopsByName(name string) []*Vertex {
-	filteredVertices := []*Vertex{}
-	name = strings.ToLower(name)
-	for _, v := range graph.GetAllVertices() {
-		if strings.Contains(v.metadata.StopNameLower, name) {
-			filteredVertices = append(filteredVertices, v)
+// pathTo follows the edges used to reach a vertex back to the source it was
+// reached from
+func (graph *SLGraph) pathTo(ctx *searchContext, label string, cameFrom map[string]hop, sourceOf map[string]Endpoint) ([]*TimedEdge, Endpoint, bool) {
+	var path []*TimedEdge
+	for {
+		prev, ok := cameFrom[label]
+		if !ok {
+			break
 		}
+		path = append(path, &TimedEdge{
+			Edge:          prev.edge,
+			DepartureTime: ctx.Time(prev.departure),
+			ArrivalTime:   ctx.Time(prev.arrival),
+		})
+		label = prev.edge.source.label // go backwards
+	}
+	source, ok := sourceOf[label]
+	if !ok {
+		log.Println("error: Didn't find came from")
+		return nil, Endpoint{}, false
 	}
-	sort.Slice(filteredVertices, func(i, j int) bool {
-		return filteredVertices[i].label < filteredVertices[j].label
-	})
-	return filteredVertices
+	slices.Reverse(path)
+	return path, source, true
+}
+
+// hop is an edge used during a search, with departure and arrival in search seconds
+type hop struct {
+	edge      *Edge
+	departure int
+	arrival   int
 }
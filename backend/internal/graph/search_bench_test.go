@@ -0,0 +1,93 @@
+package graph_test
+
+import (
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// the SL feed is large, so it's loaded once for all benchmarks
+var slGraph = sync.OnceValues(func() (*graph.SLGraph, error) {
+	return graph.Build(os.Getenv("GTFS_SOURCE"), "")
+})
+
+// helper: the graph of the feed at $GTFS_SOURCE, skipping the benchmark without one
+func benchmarkGraph(b *testing.B) *graph.SLGraph {
+	b.Helper()
+	if os.Getenv("GTFS_SOURCE") == "" {
+		b.Skip("GTFS_SOURCE not set")
+	}
+	g, err := slGraph()
+	if err != nil {
+		b.Skipf("Couldn't load the feed, err: %v", err)
+	}
+	return g
+}
+
+// helper: compare the indexed and the unindexed search on the same random
+// stop pairs, departing on a weekday morning
+func benchmarkSearches(b *testing.B, g *graph.SLGraph) {
+	vertices := g.GetAllVertices()
+	rnd := rand.New(rand.NewSource(1))
+	pairs := make([][2]*graph.Vertex, 64)
+	for i := range pairs {
+		pairs[i] = [2]*graph.Vertex{vertices[rnd.Intn(len(vertices))], vertices[rnd.Intn(len(vertices))]}
+	}
+	departure := time.Date(2026, 10, 16, 8, 0, 0, 0, g.Location())
+	profile := graph.DefaultProfile()
+	searches := map[string]func(sources, targets []graph.Endpoint, departure time.Time, profile graph.RoutingProfile) *graph.MultiRoute{
+		"indexed":   g.FindRouteMulti,
+		"unindexed": g.FindRouteMultiUnindexed,
+	}
+	// the index is built by the first search, not while measuring
+	g.FindRouteMulti([]graph.Endpoint{{Vertex: vertices[0]}}, []graph.Endpoint{{Vertex: vertices[0]}}, departure, profile)
+	for _, name := range []string{"indexed", "unindexed"} {
+		search := searches[name]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				pair := pairs[i%len(pairs)]
+				search([]graph.Endpoint{{Vertex: pair[0]}}, []graph.Endpoint{{Vertex: pair[1]}}, departure, profile)
+			}
+		})
+	}
+}
+
+func BenchmarkFindRoute_SL(b *testing.B) {
+	benchmarkSearches(b, benchmarkGraph(b))
+}
+
+func BenchmarkFindRoute_Grid(b *testing.B) {
+	benchmarkSearches(b, gridGraph(b, 30, 1))
+}
+
+// helper: the range search between random stop pairs over the longest window
+// the API allows, from a weekday morning
+func benchmarkWindows(b *testing.B, g *graph.SLGraph) {
+	vertices := g.GetAllVertices()
+	rnd := rand.New(rand.NewSource(1))
+	pairs := make([][2]*graph.Vertex, 16)
+	for i := range pairs {
+		pairs[i] = [2]*graph.Vertex{vertices[rnd.Intn(len(vertices))], vertices[rnd.Intn(len(vertices))]}
+	}
+	from := time.Date(2026, 10, 16, 7, 0, 0, 0, g.Location())
+	until := from.Add(4 * time.Hour)
+	profile := graph.DefaultProfile()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pair := pairs[i%len(pairs)]
+		g.FindRoutesInWindow(pair[0], pair[1], from, until, profile)
+	}
+}
+
+func BenchmarkFindRoutesInWindow_SL(b *testing.B) {
+	benchmarkWindows(b, benchmarkGraph(b))
+}
+
+func BenchmarkFindRoutesInWindow_Grid(b *testing.B) {
+	benchmarkWindows(b, gridGraph(b, 30, 1))
+}
@@ -0,0 +1,338 @@
+package graph
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// searchIndex is the graph laid out for route searches. Vertices get dense
+// integer IDs and their edges are kept in flat arrays, compressed sparse rows:
+// the connections of vertex i are connections[firstConnection[i]:firstConnection[i+1]].
+// A connection is every ride between two stops, sorted by departure so the
+// next one is found by binary search. The index is built on the first search
+// and thrown away when the graph changes.
+type searchIndex struct {
+	vertices        []*Vertex
+	ids             map[string]int32
+	firstConnection []int32
+	connections     []connection
+	rides           []ride
+	firstWalk       []int32
+	walks           []walk
+	states          sync.Pool // *searchState sized for the index
+}
+
+// connection is the rides from a stop to the next, rides[first:last]
+type connection struct {
+	dest         int32
+	first, last  int32
+	minDeparture int32 // of its rides, seconds since start of service day
+	maxDeparture int32
+	minDuration  int32 // the shortest ride, bounds the arrival of later departures
+	maxWait      int32 // the longest boarding wait of its rides
+}
+
+// ride is a commute edge
+type ride struct {
+	departure int32
+	arrival   int32
+	trip      int32 // dense trip ID, noTrip for edges without one
+	wait      int32 // boardingWait of the edge
+	mode      Mode
+	edge      *Edge
+}
+
+type walk struct {
+	dest int32
+	edge *Edge
+}
+
+// noTrip is the trip ID of walks and of searches not yet on a vehicle
+const noTrip int32 = -1
+
+func newSearchIndex(vertices map[string]*Vertex) *searchIndex {
+	idx := &searchIndex{
+		vertices: make([]*Vertex, 0, len(vertices)),
+		ids:      make(map[string]int32, len(vertices)),
+	}
+	for _, v := range vertices {
+		idx.vertices = append(idx.vertices, v)
+	}
+	// sorted so the IDs don't depend on map order
+	slices.SortFunc(idx.vertices, func(a, b *Vertex) int {
+		return cmp.Compare(a.label, b.label)
+	})
+	for i, v := range idx.vertices {
+		idx.ids[v.label] = int32(i)
+	}
+	trips := make(map[string]int32)
+	tripID := func(id string) int32 {
+		if id == "" {
+			return noTrip
+		}
+		if n, ok := trips[id]; ok {
+			return n
+		}
+		n := int32(len(trips))
+		trips[id] = n
+		return n
+	}
+	idx.firstConnection = make([]int32, 0, len(idx.vertices)+1)
+	idx.firstWalk = make([]int32, 0, len(idx.vertices)+1)
+	for _, v := range idx.vertices {
+		idx.firstConnection = append(idx.firstConnection, int32(len(idx.connections)))
+		idx.firstWalk = append(idx.firstWalk, int32(len(idx.walks)))
+		var rides []*Edge
+		for _, e := range v.edges {
+			if e.Metadata.TransferType == WALK_EDGE {
+				idx.walks = append(idx.walks, walk{dest: idx.ids[e.dest.label], edge: e})
+			} else {
+				rides = append(rides, e)
+			}
+		}
+		slices.SortFunc(rides, func(a, b *Edge) int {
+			return cmp.Or(
+				cmp.Compare(idx.ids[a.dest.label], idx.ids[b.dest.label]),
+				cmp.Compare(a.Metadata.Departure, b.Metadata.Departure),
+				cmp.Compare(a.Metadata.TripID, b.Metadata.TripID),
+			)
+		})
+		for i := 0; i < len(rides); {
+			dest := idx.ids[rides[i].dest.label]
+			c := connection{dest: dest, first: int32(len(idx.rides))}
+			for ; i < len(rides) && idx.ids[rides[i].dest.label] == dest; i++ {
+				m := rides[i].Metadata
+				r := ride{
+					departure: int32(m.Departure),
+					arrival:   int32(m.Arrival),
+					trip:      tripID(m.TripID),
+					wait:      int32(rides[i].boardingWait()),
+					mode:      m.Mode,
+					edge:      rides[i],
+				}
+				if c.first == int32(len(idx.rides)) {
+					c.minDeparture, c.maxDeparture, c.minDuration = r.departure, r.departure, r.arrival-r.departure
+				}
+				c.maxDeparture = r.departure
+				c.minDuration = min(c.minDuration, r.arrival-r.departure)
+				c.maxWait = max(c.maxWait, r.wait)
+				idx.rides = append(idx.rides, r)
+			}
+			c.last = int32(len(idx.rides))
+			idx.connections = append(idx.connections, c)
+		}
+	}
+	idx.firstConnection = append(idx.firstConnection, int32(len(idx.connections)))
+	idx.firstWalk = append(idx.firstWalk, int32(len(idx.walks)))
+	idx.states.New = func() any {
+		return newSearchState(len(idx.vertices))
+	}
+	return idx
+}
+
+// searchIndex returns the search index, building it if the graph changed
+// since the last search. The caller holds the read lock, so the graph can't
+// change while it's built.
+func (graph *SLGraph) searchIndex() *searchIndex {
+	if idx := graph.search.Load(); idx != nil {
+		return idx
+	}
+	graph.searchMu.Lock()
+	defer graph.searchMu.Unlock()
+	if idx := graph.search.Load(); idx != nil {
+		return idx
+	}
+	idx := newSearchIndex(graph.vertices)
+	graph.search.Store(idx)
+	return idx
+}
+
+// searchState is what a search keeps per vertex. States are reused between
+// searches: a vertex only holds values of the current search if it's stamped
+// with its generation, so nothing has to be cleared.
+type searchState struct {
+	generation uint32
+	seen       []uint32 // g, came, from and trip hold for this generation
+	closed     []uint32
+	g          []int
+	came       []hop // zero for the vertices searches start at
+	from       []int32
+	trip       []int32
+	open       openHeap
+}
+
+func newSearchState(n int) *searchState {
+	return &searchState{
+		seen:   make([]uint32, n),
+		closed: make([]uint32, n),
+		g:      make([]int, n),
+		came:   make([]hop, n),
+		from:   make([]int32, n),
+		trip:   make([]int32, n),
+	}
+}
+
+// acquire returns a search state ready for a new search
+func (idx *searchIndex) acquire() *searchState {
+	st := idx.states.Get().(*searchState)
+	st.generation++
+	if st.generation == 0 {
+		// wrapped around, old stamps could match again
+		clear(st.seen)
+		clear(st.closed)
+		st.generation = 1
+	}
+	st.open = st.open[:0]
+	return st
+}
+
+func (idx *searchIndex) release(st *searchState) {
+	idx.states.Put(st)
+}
+
+// reach records a cheaper way to a vertex, reporting whether it was cheaper
+func (st *searchState) reach(id int32, g int, h hop, from, trip int32) bool {
+	if st.seen[id] == st.generation && st.g[id] <= g {
+		return false
+	}
+	st.seen[id] = st.generation
+	st.g[id], st.came[id], st.from[id], st.trip[id] = g, h, from, trip
+	return true
+}
+
+// openItem is a vertex waiting to be expanded, cheapest estimate first
+type openItem struct {
+	f, g int
+	id   int32
+}
+
+// openHeap is a binary min-heap of open items by f
+type openHeap []openItem
+
+func (h *openHeap) push(item openItem) {
+	*h = append(*h, item)
+	q := *h
+	for i := len(q) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if q[parent].f <= q[i].f {
+			break
+		}
+		q[parent], q[i] = q[i], q[parent]
+		i = parent
+	}
+}
+
+func (h *openHeap) pop() openItem {
+	q := *h
+	top := q[0]
+	last := len(q) - 1
+	q[0] = q[last]
+	q = q[:last]
+	for i := 0; ; {
+		smallest, left, right := i, 2*i+1, 2*i+2
+		if left < len(q) && q[left].f < q[smallest].f {
+			smallest = left
+		}
+		if right < len(q) && q[right].f < q[smallest].f {
+			smallest = right
+		}
+		if smallest == i {
+			break
+		}
+		q[i], q[smallest] = q[smallest], q[i]
+		i = smallest
+	}
+	*h = q
+	return top
+}
+
+// ridePenalties are the penalties of the profile as arrays by mode, looked up
+// for every ride considered
+type ridePenalties struct {
+	excluded [MODE_FERRY + 1]bool
+	mode     [MODE_FERRY + 1]int
+	transfer int
+	least    int // the least penalty of any ride, 0 or a negative one
+}
+
+func newRidePenalties(profile *RoutingProfile) *ridePenalties {
+	p := &ridePenalties{transfer: profile.TransferPenalty}
+	for mode, excluded := range profile.ExcludedModes {
+		if mode >= 0 && mode <= MODE_FERRY {
+			p.excluded[mode] = excluded
+		}
+	}
+	for mode, penalty := range profile.ModePenalties {
+		if mode >= 0 && mode <= MODE_FERRY {
+			p.mode[mode] = penalty
+		}
+	}
+	for _, penalty := range p.mode {
+		p.least = min(p.least, penalty, penalty+p.transfer)
+	}
+	return p
+}
+
+// nextRide finds the cheapest ride of a connection when at its stop at now
+// having arrived with trip, as calculateG would for each of its edges. Rides
+// are scanned by departure from the first not yet gone, and the scan stops
+// once a ride departs too late to be cheaper than the best one found.
+// change is the time needed to board another trip, if canChange.
+func (idx *searchIndex) nextRide(ctx *searchContext, c *connection, now int, trip int32, change int, canChange bool, p *ridePenalties) (hop, int32, int, bool) {
+	rides := idx.rides[c.first:c.last]
+	best, bestTrip, bestCost, found := hop{}, noTrip, 0, false
+	firstDay := floorDiv(now-int(c.maxDeparture), SECONDS_PER_DAY) - 1
+	lastDay := floorDiv(now+max(change, 0)+int(c.maxWait)-int(c.minDeparture), SECONDS_PER_DAY) + serviceDayLookahead
+	for day := firstDay; day <= lastDay; day++ {
+		offset := ctx.dayOffset(day)
+		if found && offset+int(c.minDeparture+c.minDuration)-now+p.least >= bestCost {
+			break
+		}
+		// binary search for the first ride departing at or after now
+		lo, hi := 0, len(rides)
+		for lo < hi {
+			mid := int(uint(lo+hi) >> 1)
+			if offset+int(rides[mid].departure) < now {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		for i := lo; i < len(rides); i++ {
+			r := &rides[i]
+			departure := offset + int(r.departure)
+			if found && departure+int(c.minDuration)-now+p.least >= bestCost {
+				break
+			}
+			if p.excluded[r.mode] {
+				continue
+			}
+			earliest, penalty := now, 0
+			if r.trip != trip {
+				earliest += int(r.wait)
+				penalty = p.mode[r.mode]
+				if trip != noTrip {
+					if !canChange {
+						continue
+					}
+					earliest += change
+					penalty += p.transfer
+				}
+			}
+			// the service days nextDeparture looks at for the edge
+			edgeDay := floorDiv(earliest-int(r.departure), SECONDS_PER_DAY)
+			if departure < earliest || day < edgeDay-1 || day > edgeDay+serviceDayLookahead {
+				continue
+			}
+			if !ctx.runs(day, r.edge.Metadata.ServiceID) {
+				continue
+			}
+			arrival := offset + int(r.arrival)
+			if cost := arrival - now + penalty; !found || cost < bestCost {
+				best, bestTrip, bestCost, found = hop{edge: r.edge, departure: departure, arrival: arrival}, r.trip, cost, true
+			}
+		}
+	}
+	return best, bestTrip, bestCost, found
+}
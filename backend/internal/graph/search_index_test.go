@@ -0,0 +1,145 @@
+package graph_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/Durelius/next-week/internal/graph"
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// The indexed search is checked against the unindexed one, which is only
+// reachable from the tests of this directory through export_test.go. The
+// other tests of the package are in test/graph_test.
+
+// helper: services on weekdays and on weekends during 2026
+func gridCalendar() *graph.ServiceCalendar {
+	var start, end gtfs.Date
+	if err := start.UnmarshalCSV("20260101"); err != nil {
+		panic(err)
+	}
+	if err := end.UnmarshalCSV("20261231"); err != nil {
+		panic(err)
+	}
+	return graph.NewServiceCalendar([]*gtfs.Calendar{
+		{ServiceID: "weekday", Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true, StartDate: start, EndDate: end},
+		{ServiceID: "weekend", Saturday: true, Sunday: true, StartDate: start, EndDate: end},
+	}, nil)
+}
+
+// helper: parse a "YYYY-MM-DD HH:MM" time in UTC
+func at(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// helper: a grid of stops 550 m apart, with lines of every mode along the rows
+// and columns running in both directions at odd times on weekdays and
+// weekends, some only by headway, and walks between neighbors. Times are
+// random to the second so that two ways are rarely equally cheap.
+func gridGraph(t testing.TB, size int, seed int64) *graph.SLGraph {
+	rnd := rand.New(rand.NewSource(seed))
+	g := graph.New()
+	g.SetCalendar(gridCalendar())
+	stops := make([][]*graph.Vertex, size)
+	for i := range stops {
+		stops[i] = make([]*graph.Vertex, size)
+		for j := range stops[i] {
+			id := fmt.Sprintf("%d-%d", i, j)
+			v := graph.NewVertex(id)
+			v.SetMetadata(&graph.Stop{
+				StopID:        id,
+				StopName:      id,
+				StopLatitude:  fmt.Sprintf("%.4f", 59.30+0.005*float64(i)),
+				StopLongitude: fmt.Sprintf("%.4f", 18.00+0.01*float64(j)),
+			})
+			g.AddVertex(v)
+			stops[i][j] = v
+		}
+	}
+	mustAdd := func(from, to *graph.Vertex, properties graph.EdgeProperties) {
+		if _, err := g.AddEdge(from, to, properties); err != nil {
+			t.Fatalf("AddEdge(%s -> %s): %v", from.Label(), to.Label(), err)
+		}
+	}
+	for i := range size {
+		for j := range size {
+			if i+1 < size {
+				mustAdd(stops[i][j], stops[i+1][j], graph.EdgeProperties{TransferType: graph.WALK_EDGE, Distance: 350})
+				mustAdd(stops[i+1][j], stops[i][j], graph.EdgeProperties{TransferType: graph.WALK_EDGE, Distance: 350})
+			}
+		}
+	}
+	line := func(name string, route []*graph.Vertex) {
+		mode := graph.Mode(1 + rnd.Intn(int(graph.MODE_FERRY)))
+		service := []string{"weekday", "weekend"}[rnd.Intn(2)]
+		headway := 300 + rnd.Intn(900)
+		estimated := 0 // the headway of lines without exact times
+		if rnd.Intn(3) == 0 {
+			estimated = headway
+		}
+		for n, dep := 0, rnd.Intn(headway); dep < 30*3600; n, dep = n+1, dep+headway {
+			trip := fmt.Sprintf("%s-%d", name, n)
+			for k := 0; k+1 < len(route); k++ {
+				arr := dep + 90 + rnd.Intn(60)
+				mustAdd(route[k], route[k+1], graph.EdgeProperties{
+					TripID:       trip,
+					ServiceID:    service,
+					Mode:         mode,
+					TransferType: graph.COMMUTE_EDGE,
+					Departure:    dep,
+					Arrival:      arr,
+					Headway:      estimated,
+				})
+				dep = arr + rnd.Intn(30)
+			}
+		}
+	}
+	for i := range size {
+		row, column := make([]*graph.Vertex, size), make([]*graph.Vertex, size)
+		for j := range size {
+			row[j], column[j] = stops[i][j], stops[j][i]
+		}
+		line(fmt.Sprintf("row%d", i), row)
+		line(fmt.Sprintf("column%d", i), column)
+		reversed := make([]*graph.Vertex, size)
+		for j := range size {
+			reversed[j] = row[size-1-j]
+		}
+		line(fmt.Sprintf("back%d", i), reversed)
+	}
+	return g
+}
+
+func TestFindRouteMulti_IndexedMatchesUnindexed(t *testing.T) {
+	g := gridGraph(t, 8, 1)
+	vertices := g.GetAllVertices()
+	rnd := rand.New(rand.NewSource(2))
+	penalized := graph.DefaultProfile()
+	penalized.ModePenalties = map[graph.Mode]int{graph.MODE_BUS: 600, graph.MODE_METRO: -60}
+	penalized.ExcludedModes = map[graph.Mode]bool{graph.MODE_FERRY: true}
+	profiles := map[string]graph.RoutingProfile{"default": graph.DefaultProfile(), "penalized": penalized}
+	departures := []time.Time{
+		at(t, "2026-10-16 07:55"), // a weekday
+		at(t, "2026-10-16 23:40"), // continues into the weekend
+		at(t, "2026-10-18 12:03"), // a sunday
+	}
+	for name, profile := range profiles {
+		for n := 0; n < 100; n++ {
+			from, to := vertices[rnd.Intn(len(vertices))], vertices[rnd.Intn(len(vertices))]
+			departure := departures[n%len(departures)]
+			sources, targets := []graph.Endpoint{{Vertex: from}}, []graph.Endpoint{{Vertex: to}}
+			indexed := g.FindRouteMulti(sources, targets, departure, profile)
+			unindexed := g.FindRouteMultiUnindexed(sources, targets, departure, profile)
+			if (indexed == nil) != (unindexed == nil) || indexed != nil && indexed.Cost != unindexed.Cost {
+				t.Errorf("%s %s -> %s at %s: indexed %+v, unindexed %+v", name, from.Label(), to.Label(), departure, indexed, unindexed)
+			}
+		}
+	}
+}
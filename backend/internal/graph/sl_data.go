@@ -1,30 +1,71 @@
 package graph
 
 import (
-	"fmt"
+	"errors"
 	"log"
-	"os"
 	"sort"
 	"strings"
+	"time"
 
-	"github.com/gocarina/gocsv"
+	"github.com/Durelius/next-week/internal/gtfs"
 )
 
-func (graph *SLGraph) init() error {
+func (graph *SLGraph) init(feed *gtfs.Feed) error {
 
-	_, _, stopTimes, stops, _, err := load()
+	agencies, routes, stopTimes, stops, trips, err := load(feed)
 	if err != nil {
 		return err
 	}
+	if len(agencies) > 0 {
+		loc, err := time.LoadLocation(agencies[0].AgencyTimezone)
+		if err != nil {
+			log.Printf("Couldn't load agency timezone, using local time, err: %v", err)
+		} else {
+			graph.location = loc
+		}
+	}
+	calendars, calendarDates, err := loadCalendar(feed)
+	if err != nil {
+		return err
+	}
+	graph.calendar = NewServiceCalendar(calendars, calendarDates)
+	var transfers []*gtfs.Transfer
+	if err := feed.UnmarshalOptional(gtfs.FILE_TRANSFERS, &transfers); err != nil {
+		return err
+	}
+	graph.transfers = NewTransferRules(transfers)
+	modes := make(map[string]Mode) // routeID -> mode
+	for _, route := range routes {
+		modes[route.RouteID] = ModeFromRouteType(route.RouteType)
+	}
+	tripsByID := make(map[string]*gtfs.Trip)
+	for _, trip := range trips {
+		tripsByID[trip.TripID] = trip
+	}
+	infos := tripInfos(agencies, routes, trips)
+	// stops are boarded and become vertices, stations group them, and
+	// entrances and nodes inside stations aren't used
+	var platforms, stations []*Stop
 	for _, stop := range stops {
+		switch locationType(stop) {
+		case gtfs.LOCATION_STOP:
+			platforms = append(platforms, stop)
+		case gtfs.LOCATION_STATION:
+			stations = append(stations, stop)
+		}
+	}
+	graph.SetParentStations(stations)
+	for _, stop := range platforms {
 		v := NewVertex(stop.StopID)
 		stop.StopNameLower = strings.ToLower(stop.StopName)
-		v.SetMetadata(stop)
+		if err := v.SetMetadata(stop); err != nil {
+			log.Printf("Stop %s has no valid coordinates, no walking edges added, err: %v", stop.StopID, err)
+		}
 		graph.AddVertex(v)
 	}
-	stopTimeMap := make(map[string][]StopTimes)
+	stopTimeMap := make(map[string][]*gtfs.StopTime)
 	for _, stopTime := range stopTimes {
-		stopTimeMap[stopTime.TripID] = append(stopTimeMap[stopTime.TripID], *stopTime)
+		stopTimeMap[stopTime.TripID] = append(stopTimeMap[stopTime.TripID], stopTime)
 	}
 	for tripID, times := range stopTimeMap {
 
@@ -34,120 +75,178 @@ func (graph *SLGraph) init() error {
 
 		stopTimeMap[tripID] = times
 	}
-	for _, times := range stopTimeMap {
-		for i := 0; i < len(times)-1; i++ {
-			from := times[i]
-			to := times[i+1]
-			fromVertice := graph.GetVertexByID(from.StopID)
-			toVertice := graph.GetVertexByID(to.StopID)
-			edgeProps := EdgeProperties{
-				TripID:         from.TripID,
-				Departure:      toMinutes(from.DepartureTime),
-				Arrival:        toMinutes(to.ArrivalTime),
-				TransferType:   COMMUTE_EDGE,
-				SourceStopName: fromVertice.metadata.StopName,
-				DestStopName:   toVertice.metadata.StopName,
-			}
-			if _, err := graph.AddEdge(fromVertice, toVertice, edgeProps); err != nil {
+	var frequencies []*gtfs.Frequency
+	if err := feed.UnmarshalOptional(gtfs.FILE_FREQUENCIES, &frequencies); err != nil {
+		return err
+	}
+	frequenciesByTrip := make(map[string][]*gtfs.Frequency)
+	for _, f := range frequencies {
+		frequenciesByTrip[f.TripID] = append(frequenciesByTrip[f.TripID], f)
+	}
+	for tripID, times := range stopTimeMap {
+		template := EdgeProperties{TripID: tripID, TransferType: COMMUTE_EDGE}
+		if trip, ok := tripsByID[tripID]; ok {
+			template.ServiceID = trip.ServiceID
+			template.Mode = modes[trip.RouteID]
+			template.TripInfo = infos[tripID]
+		}
+		if tripFrequencies, ok := frequenciesByTrip[tripID]; ok {
+			if err := graph.addFrequencyTripEdges(template, times, tripFrequencies); err != nil {
 				return err
 			}
+			continue
+		}
+		if err := graph.addTripEdges(template, times, 0); err != nil {
+			return err
+		}
+	}
+	if err := graph.addTransferEdges(platforms); err != nil {
+		return err
+	}
+	return graph.addFeedTransferEdges()
+}
+
+// addTripEdges adds the commute edges between the consecutive stops of a run
+// of a trip, shift is added to every stop time
+func (graph *SLGraph) addTripEdges(template EdgeProperties, times []*gtfs.StopTime, shift int) error {
+	for i := 0; i < len(times)-1; i++ {
+		from := times[i]
+		to := times[i+1]
+		fromVertice := graph.GetVertexByID(from.StopID)
+		toVertice := graph.GetVertexByID(to.StopID)
+		if fromVertice == nil || toVertice == nil {
+			log.Printf("Skipping stop time of trip %s, stop %s or %s isn't boarded", from.TripID, from.StopID, to.StopID)
+			continue
+		}
+		if !from.DepartureTime.IsSet() || !to.ArrivalTime.IsSet() {
+			// stops between timepoints may leave out their times
+			log.Printf("Skipping stop time of trip %s, no time at stop %s or %s", from.TripID, from.StopID, to.StopID)
+			continue
+		}
+		edgeProps := template
+		edgeProps.Departure = int(from.DepartureTime) + shift
+		edgeProps.Arrival = int(to.ArrivalTime) + shift
+		edgeProps.StopSequence = from.StopSequence
+		edgeProps.SourceStopName = fromVertice.metadata.StopName
+		edgeProps.DestStopName = toVertice.metadata.StopName
+		if _, err := graph.AddEdge(fromVertice, toVertice, edgeProps); err != nil {
+			if errors.Is(err, ErrEdgeAlreadyExists) {
+				// only when the feed repeats a stop sequence of the trip
+				log.Printf("Skipping stop time of trip %s, stop sequence %d is already connected to %s", from.TripID, from.StopSequence, to.StopID)
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// addFeedTransferEdges adds walking edges for transfers between stops declared
+// in transfers.txt that aren't already connected
+func (graph *SLGraph) addFeedTransferEdges() error {
+	defaultProfile := DefaultProfile()
+	for _, t := range graph.transfers.All() {
+		if t.FromStopID == t.ToStopID || t.TransferType == gtfs.TRANSFER_NOT_POSSIBLE {
+			continue
+		}
+		from := graph.GetVertexByID(t.FromStopID)
+		to := graph.GetVertexByID(t.ToStopID)
+		if from == nil || to == nil || graph.ContainsTripEdge(from, to, "") {
+			continue
+		}
+		// the walking time comes from the profile like for other walking
+		// edges, walkTime keeps it at least the time declared by the feed
+		edgeProps := EdgeProperties{
+			Arrival:        max(int(t.MinTransferTime), 60),
+			TransferType:   WALK_EDGE,
+			SourceStopName: from.metadata.StopName,
+			DestStopName:   to.metadata.StopName,
+		}
+		if distance, err := ApproxDistanceMeters(from.metadata, to.metadata); err == nil {
+			edgeProps.Distance = distance
+			edgeProps.Arrival = max(edgeProps.Arrival, defaultProfile.walkSeconds(distance))
+		} else {
+			log.Printf("Couldn't measure transfer from %s to %s, err: %v", t.FromStopID, t.ToStopID, err)
+		}
+		if _, err := graph.AddEdge(from, to, edgeProps); err != nil {
+			return err
 		}
 	}
-	return graph.addTransferEdges(stops)
+	return nil
 }
 func (graph *SLGraph) addTransferEdges(stops []*Stop) error {
-	for i, a := range stops {
-		for j, b := range stops {
-			if i >= j {
+	defaultProfile := DefaultProfile()
+	for _, a := range stops {
+		if !a.HasCoordinates() {
+			continue // logged when loaded
+		}
+		for _, near := range graph.StopsWithinRadius(a.Lat, a.Lon, MAX_WALK_DISTANCE) {
+			b := near.Stop
+			if a.StopID >= b.StopID {
 				continue
 			} // skip self and avoid duplicates
 			if a.StopName == b.StopName {
 				continue
 			}
-			dist, err := ApproxDistanceMeters(a, b)
-			if err != nil {
-				log.Printf("Couldn't calculate distance, err: %v", err)
-				continue
+			// the walking time is calculated by the routing profile,
+			// Arrival holds the time at the default walking speed
+			edgeProps := EdgeProperties{
+				Departure:    0,
+				Arrival:      defaultProfile.walkSeconds(near.Distance),
+				TransferType: WALK_EDGE,
+				Distance:     near.Distance,
 			}
+			from := graph.GetVertexByID(a.StopID)
+			to := near.Vertex
+			edgeProps.SourceStopName = from.metadata.StopName
+			edgeProps.DestStopName = to.metadata.StopName
 
-			if dist < 400 {
-				// 80 meters per minute
-				walkMinutes := int(dist / 80.0)
-				//minimum of 1 minute
-				if walkMinutes == 0 {
-					walkMinutes = 1
-				}
-				edgeProps := EdgeProperties{
-					Departure:    0,
-					Arrival:      walkMinutes,
-					TransferType: WALK_EDGE,
-				}
-				from := graph.GetVertexByID(a.StopID)
-				to := graph.GetVertexByID(b.StopID)
-				edgeProps.SourceStopName = from.metadata.StopName
-				edgeProps.DestStopName = to.metadata.StopName
-
-				if _, err := graph.AddEdge(from, to, edgeProps); err != nil {
-					return err
-				}
-				if _, err := graph.AddEdge(to, from, edgeProps); err != nil {
-					return err
-				}
+			if _, err := graph.AddEdge(from, to, edgeProps); err != nil {
+				return err
+			}
+			edgeProps.SourceStopName, edgeProps.DestStopName = edgeProps.DestStopName, edgeProps.SourceStopName
+			if _, err := graph.AddEdge(to, from, edgeProps); err != nil {
+				return err
 			}
 		}
 	}
 	return nil
 }
-func load() ([]*Agency, []*Routes, []*StopTimes, []*Stop, []*Trips, error) {
-	var agencies []*Agency
-	file, err := os.Open(PATH_AGENCY)
-	if err != nil {
-		return nil, nil, nil, nil, nil, err
-	}
-	defer file.Close()
-	if err := gocsv.Unmarshal(file, &agencies); err != nil {
-		return nil, nil, nil, nil, nil, err
-	}
-	var routes []*Routes
-	file, err = os.Open(PATH_ROUTES)
-	if err != nil {
-		return nil, nil, nil, nil, nil, err
-	}
-	defer file.Close()
-	if err := gocsv.Unmarshal(file, &routes); err != nil {
+
+// load reads the required files of the feed
+func load(feed *gtfs.Feed) ([]*gtfs.Agency, []*gtfs.Route, []*gtfs.StopTime, []*Stop, []*gtfs.Trip, error) {
+	var agencies []*gtfs.Agency
+	if err := feed.Unmarshal(gtfs.FILE_AGENCY, &agencies); err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
-	var stopTimes []*StopTimes
-	file, err = os.Open(PATH_STOPTIMES)
-	if err != nil {
+	var routes []*gtfs.Route
+	if err := feed.Unmarshal(gtfs.FILE_ROUTES, &routes); err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
-	defer file.Close()
-	if err := gocsv.Unmarshal(file, &stopTimes); err != nil {
+	var stopTimes []*gtfs.StopTime
+	if err := feed.Unmarshal(gtfs.FILE_STOP_TIMES, &stopTimes); err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
 	var stops []*Stop
-	file, err = os.Open(PATH_STOPS)
-	if err != nil {
+	if err := feed.Unmarshal(gtfs.FILE_STOPS, &stops); err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
-	defer file.Close()
-	if err := gocsv.Unmarshal(file, &stops); err != nil {
+	var trips []*gtfs.Trip
+	if err := feed.Unmarshal(gtfs.FILE_TRIPS, &trips); err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
-	var trips []*Trips
-	file, err = os.Open(PATH_TRIPS)
-	if err != nil {
-		return nil, nil, nil, nil, nil, err
+	return agencies, routes, stopTimes, stops, trips, nil
+}
+
+// loadCalendar reads calendar.txt and calendar_dates.txt, both files are optional
+func loadCalendar(feed *gtfs.Feed) ([]*gtfs.Calendar, []*gtfs.CalendarDate, error) {
+	var calendars []*gtfs.Calendar
+	if err := feed.UnmarshalOptional(gtfs.FILE_CALENDAR, &calendars); err != nil {
+		return nil, nil, err
 	}
-	defer file.Close()
-	if err := gocsv.Unmarshal(file, &trips); err != nil {
-		return nil, nil, nil, nil, nil, err
+	var calendarDates []*gtfs.CalendarDate
+	if err := feed.UnmarshalOptional(gtfs.FILE_CALENDAR_DATES, &calendarDates); err != nil {
+		return nil, nil, err
 	}
-	return agencies, routes, stopTimes, stops, trips, err
-}
-func toMinutes(t string) int {
-	var h, m, s int
-	fmt.Sscanf(t, "%d:%d:%d", &h, &m, &s)
-	return h*60 + m
+	return calendars, calendarDates, nil
 }
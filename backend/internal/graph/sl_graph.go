@@ -1,54 +1,138 @@
 package graph
 
 import (
+	"errors"
+	"io/fs"
 	"log"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/Durelius/next-week/internal/gtfs"
 )
 
+// SLGraph is safe for concurrent use. Searches and lookups share a read lock
+// for their whole run, so any number of them may run in parallel, while
+// adding or removing vertices and edges takes the write lock and waits for
+// them. Vertices and edges handed out are read-only, and walking their edge
+// lists directly (Vertex.Edges) is only safe while nothing mutates the graph,
+// use EdgesOf otherwise.
 type SLGraph struct {
-	vertices      map[string]*Vertex
-	edges         map[string]map[string]*Edge // source -> dest -> edge
-	verticesCount uint32
-	edgesCount    uint32
+	mu             sync.RWMutex
+	vertices       map[string]*Vertex
+	edges          map[string]map[string]map[string]*Edge // source -> dest -> tripID -> edge
+	calendar       *ServiceCalendar
+	transfers      *TransferRules
+	stopIndex      *StopIndex
+	parentStations map[string]*Stop             // location type 1 stops by ID
+	stations       atomic.Pointer[stationIndex] // built on first use, reset by changes
+	stationsMu     sync.Mutex
+	search         atomic.Pointer[searchIndex] // built on the first route search, reset by changes
+	searchMu       sync.Mutex
+	location       *time.Location // timezone of the feed
+	verticesCount  uint32
+	edgesCount     uint32
 }
 
 // New creates a New empty SL graph
 func New() *SLGraph {
 	return &SLGraph{
-		vertices: make(map[string]*Vertex),
-		edges:    make(map[string]map[string]*Edge),
+		vertices:  make(map[string]*Vertex),
+		edges:     make(map[string]map[string]map[string]*Edge),
+		stopIndex: NewStopIndex(),
 	}
 }
 
-var (
-	instance *SLGraph
-	once     sync.Once
-)
+// Build creates the graph of the GTFS feed at source. With a snapshotPath the
+// graph is read from the snapshot if it was built from the same feed, and
+// otherwise built from the feed and saved there for the next start.
+func Build(source, snapshotPath string) (*SLGraph, error) {
+	feed, err := gtfs.Load(source)
+	if err != nil {
+		return nil, err
+	}
+	defer feed.Close()
+	if snapshotPath == "" {
+		log.Printf("loading graph data from %s....", source)
+		return NewFromFeed(feed)
+	}
+	checksum, err := feed.Checksum()
+	if err != nil {
+		return nil, err
+	}
+	graph, err := LoadSnapshot(snapshotPath, checksum)
+	if err == nil {
+		log.Printf("loaded graph snapshot %s", snapshotPath)
+		return graph, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Couldn't use graph snapshot %s, rebuilding it, err: %v", snapshotPath, err)
+	}
+	log.Printf("loading graph data from %s....", source)
+	graph, err = NewFromFeed(feed)
+	if err != nil {
+		return nil, err
+	}
+	if err := graph.SaveSnapshot(snapshotPath, checksum); err != nil {
+		log.Printf("Couldn't save graph snapshot %s, err: %v", snapshotPath, err)
+	}
+	return graph, nil
+}
+
+// NewFromFeed creates a graph from a GTFS feed
+func NewFromFeed(feed *gtfs.Feed) (*SLGraph, error) {
+	graph := New()
+	if err := graph.init(feed); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
 
-// NewWithData creates a non-empty SL graph filled with data from hard-coded .CSV files
-func NewWithData() (*SLGraph, error) {
-	var initErr error
-	once.Do(func() {
-		graph := New()
-		log.Println("loading graph data....")
-		if err := graph.init(); err != nil {
-			initErr = err
-			return
-		}
-		instance = graph
-	})
-	if initErr != nil {
-		return nil, initErr
-	}
-	return instance, nil
-}
-func Instance() *SLGraph {
-	if instance == nil {
-		log.Fatal("graph is nil, call NewWithData first")
-	}
-	return instance
+// Calendar returns the service calendar of the loaded feed
+func (graph *SLGraph) Calendar() *ServiceCalendar {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	return graph.calendar
 }
+
+// Location returns the timezone the timetable is expressed in
+func (graph *SLGraph) Location() *time.Location {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	if graph.location == nil {
+		return time.Local
+	}
+	return graph.location
+}
+
+// SetCalendar replaces the service calendar used to decide which trips run on a date
+func (graph *SLGraph) SetCalendar(calendar *ServiceCalendar) {
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+	graph.calendar = calendar
+}
+
+// Transfers returns the transfer rules of the loaded feed
+func (graph *SLGraph) Transfers() *TransferRules {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	return graph.transfers
+}
+
+// SetTransfers replaces the transfer rules used to decide how long changing vehicle takes
+func (graph *SLGraph) SetTransfers(transfers *TransferRules) {
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+	graph.transfers = transfers
+}
+
+// changed throws away the indexes built from the vertices and edges, the
+// caller holds the write lock
+func (graph *SLGraph) changed() {
+	graph.stations.Store(nil)
+	graph.search.Store(nil)
+}
+
 func (graph *SLGraph) Order() uint32 {
 	return atomic.LoadUint32(&graph.verticesCount)
 }
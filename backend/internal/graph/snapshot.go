@@ -0,0 +1,527 @@
+package graph
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// A snapshot is the built graph in a compact binary file, so the server can
+// start without parsing the feed and generating the walking edges again.
+//
+// Layout: the magic bytes, the format version, the checksum of the feed it was
+// built from, then the location, calendar, transfer rules, stations, stops,
+// trip infos and edges. Integers are varints and every string is written once,
+// later uses refer to it by index. The file is read front to back as a stream.
+const (
+	snapshotMagic = "SLGRAPH\n"
+	// SNAPSHOT_VERSION changes whenever the layout does, older snapshots are rebuilt
+	SNAPSHOT_VERSION = 3
+)
+
+var (
+	ErrSnapshotStale   = errors.New("snapshot was built from another feed")
+	ErrSnapshotVersion = errors.New("snapshot has an unsupported version")
+	ErrSnapshotCorrupt = errors.New("snapshot is corrupt")
+)
+
+// snapshotWriter encodes values, keeping the first error
+type snapshotWriter struct {
+	w       *bufio.Writer
+	strings map[string]uint64
+	err     error
+	buf     [binary.MaxVarintLen64]byte
+}
+
+func (sw *snapshotWriter) write(p []byte) {
+	if sw.err == nil {
+		_, sw.err = sw.w.Write(p)
+	}
+}
+
+func (sw *snapshotWriter) uvarint(v uint64) {
+	sw.write(sw.buf[:binary.PutUvarint(sw.buf[:], v)])
+}
+
+func (sw *snapshotWriter) varint(v int64) {
+	sw.write(sw.buf[:binary.PutVarint(sw.buf[:], v)])
+}
+
+func (sw *snapshotWriter) float(f float64) {
+	binary.LittleEndian.PutUint64(sw.buf[:8], math.Float64bits(f))
+	sw.write(sw.buf[:8])
+}
+
+// str writes the index of an earlier string, or 0 followed by a new one
+func (sw *snapshotWriter) str(s string) {
+	if index, ok := sw.strings[s]; ok {
+		sw.uvarint(index + 1)
+		return
+	}
+	sw.strings[s] = uint64(len(sw.strings))
+	sw.uvarint(0)
+	sw.uvarint(uint64(len(s)))
+	sw.write([]byte(s))
+}
+
+// date writes a feed date as YYYYMMDD, empty for no date
+func (sw *snapshotWriter) date(d gtfs.Date) {
+	s, _ := d.MarshalCSV()
+	sw.str(s)
+}
+
+// flag writes a flag as 0 or 1
+func (sw *snapshotWriter) flag(b gtfs.Bool) {
+	if b {
+		sw.varint(1)
+	} else {
+		sw.varint(0)
+	}
+}
+
+// snapshotReader decodes values, keeping the first error
+type snapshotReader struct {
+	r       *bufio.Reader
+	strings []string
+	err     error
+}
+
+func (sr *snapshotReader) fail(err error) {
+	if sr.err == nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("%w: unexpected end of file", ErrSnapshotCorrupt)
+		}
+		sr.err = err
+	}
+}
+
+func (sr *snapshotReader) uvarint() uint64 {
+	if sr.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(sr.r)
+	if err != nil {
+		sr.fail(err)
+	}
+	return v
+}
+
+func (sr *snapshotReader) varint() int64 {
+	if sr.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(sr.r)
+	if err != nil {
+		sr.fail(err)
+	}
+	return v
+}
+
+func (sr *snapshotReader) int() int {
+	return int(sr.varint())
+}
+
+// count reads a length, bounded so a corrupt file can't allocate everything
+func (sr *snapshotReader) count() int {
+	n := sr.uvarint()
+	if n > math.MaxInt32 {
+		sr.fail(fmt.Errorf("%w: count %d", ErrSnapshotCorrupt, n))
+		return 0
+	}
+	return int(n)
+}
+
+func (sr *snapshotReader) float() float64 {
+	var b [8]byte
+	if sr.err != nil {
+		return 0
+	}
+	if _, err := io.ReadFull(sr.r, b[:]); err != nil {
+		sr.fail(err)
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b[:]))
+}
+
+func (sr *snapshotReader) date() gtfs.Date {
+	var d gtfs.Date
+	if err := d.UnmarshalCSV(sr.str()); err != nil {
+		sr.fail(fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err))
+	}
+	return d
+}
+
+func (sr *snapshotReader) flag() gtfs.Bool {
+	return sr.int() == 1
+}
+
+func (sr *snapshotReader) str() string {
+	index := sr.uvarint()
+	if sr.err != nil {
+		return ""
+	}
+	if index > 0 {
+		if index > uint64(len(sr.strings)) {
+			sr.fail(fmt.Errorf("%w: string %d", ErrSnapshotCorrupt, index))
+			return ""
+		}
+		return sr.strings[index-1]
+	}
+	b := make([]byte, sr.count())
+	if _, err := io.ReadFull(sr.r, b); err != nil {
+		sr.fail(err)
+		return ""
+	}
+	s := string(b)
+	sr.strings = append(sr.strings, s)
+	return s
+}
+
+// WriteSnapshot writes the graph as a snapshot of the feed with the checksum
+func (graph *SLGraph) WriteSnapshot(w io.Writer, checksum [sha256.Size]byte) error {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	sw := &snapshotWriter{w: bufio.NewWriter(w), strings: make(map[string]uint64)}
+	sw.write([]byte(snapshotMagic))
+	sw.uvarint(SNAPSHOT_VERSION)
+	sw.write(checksum[:])
+
+	location := ""
+	if graph.location != nil {
+		location = graph.location.String()
+	}
+	sw.str(location)
+
+	var services, exceptions []string
+	if graph.calendar != nil {
+		for serviceID := range graph.calendar.services {
+			services = append(services, serviceID)
+		}
+		for serviceID := range graph.calendar.exceptions {
+			exceptions = append(exceptions, serviceID)
+		}
+	}
+	sort.Strings(services)
+	sw.uvarint(uint64(len(services)))
+	for _, serviceID := range services {
+		c := graph.calendar.services[serviceID]
+		sw.str(c.ServiceID)
+		for _, day := range []gtfs.Bool{c.Monday, c.Tuesday, c.Wednesday, c.Thursday, c.Friday, c.Saturday, c.Sunday} {
+			sw.flag(day)
+		}
+		sw.date(c.StartDate)
+		sw.date(c.EndDate)
+	}
+	sort.Strings(exceptions)
+	sw.uvarint(uint64(len(exceptions)))
+	for _, serviceID := range exceptions {
+		dates := graph.calendar.exceptions[serviceID]
+		sw.str(serviceID)
+		sw.uvarint(uint64(len(dates)))
+		for _, date := range sortedKeys(dates) {
+			sw.str(date)
+			sw.varint(int64(dates[date]))
+		}
+	}
+
+	transfers := graph.transfers.All()
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].FromStopID != transfers[j].FromStopID {
+			return transfers[i].FromStopID < transfers[j].FromStopID
+		}
+		return transfers[i].ToStopID < transfers[j].ToStopID
+	})
+	sw.uvarint(uint64(len(transfers)))
+	for _, t := range transfers {
+		sw.str(t.FromStopID)
+		sw.str(t.ToStopID)
+		sw.varint(int64(t.TransferType))
+		sw.varint(int64(t.MinTransferTime))
+	}
+
+	stations := make([]*Stop, 0, len(graph.parentStations))
+	for _, station := range graph.parentStations {
+		stations = append(stations, station)
+	}
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].StopID < stations[j].StopID
+	})
+	sw.uvarint(uint64(len(stations)))
+	for _, station := range stations {
+		sw.str(station.StopID)
+		sw.str(station.StopName)
+		sw.str(station.StopLatitude)
+		sw.str(station.StopLongitude)
+	}
+
+	vertices := make([]*Vertex, 0, len(graph.vertices))
+	for _, v := range graph.vertices {
+		vertices = append(vertices, v)
+	}
+	sort.Slice(vertices, func(i, j int) bool {
+		return vertices[i].label < vertices[j].label
+	})
+	vertexIndex := make(map[*Vertex]uint64, len(vertices))
+	sw.uvarint(uint64(len(vertices)))
+	for i, v := range vertices {
+		vertexIndex[v] = uint64(i)
+		sw.str(v.label)
+		stop := v.metadata
+		if stop == nil {
+			stop = &Stop{}
+		}
+		sw.str(stop.StopID)
+		sw.str(stop.StopName)
+		sw.str(stop.StopLatitude)
+		sw.str(stop.StopLongitude)
+		sw.str(stop.LocationType)
+		sw.str(stop.ParentStation)
+	}
+
+	// trip infos are shared by the edges of a trip, 0 is none
+	infoIndex := make(map[*TripInfo]uint64)
+	var infos []*TripInfo
+	for _, v := range vertices {
+		for _, e := range v.edges {
+			if info := e.Metadata.TripInfo; info != nil && infoIndex[info] == 0 {
+				infos = append(infos, info)
+				infoIndex[info] = uint64(len(infos))
+			}
+		}
+	}
+	sw.uvarint(uint64(len(infos)))
+	for _, info := range infos {
+		sw.str(info.RouteID)
+		sw.str(info.RouteShortName)
+		sw.str(info.RouteLongName)
+		sw.str(info.TripHeadsign)
+		sw.str(info.TripShortName)
+		sw.str(info.AgencyID)
+		sw.str(info.AgencyName)
+	}
+
+	// edges in the order of the outgoing edges, so searches visit them the same way
+	sw.uvarint(uint64(graph.Size()))
+	for _, v := range vertices {
+		for _, e := range v.edges {
+			m := e.Metadata
+			sw.uvarint(vertexIndex[e.source])
+			sw.uvarint(vertexIndex[e.dest])
+			sw.str(m.TripID)
+			sw.str(m.ServiceID)
+			sw.varint(int64(m.Departure))
+			sw.varint(int64(m.Arrival))
+			sw.uvarint(uint64(m.TransferType))
+			sw.uvarint(uint64(m.Mode))
+			sw.float(m.Distance)
+			sw.varint(int64(m.Headway))
+			sw.varint(int64(m.StopSequence))
+			sw.uvarint(infoIndex[m.TripInfo])
+		}
+	}
+	if sw.err != nil {
+		return sw.err
+	}
+	return sw.w.Flush()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ReadSnapshot reads a graph written by WriteSnapshot. It returns
+// ErrSnapshotStale if the snapshot was built from a feed with another
+// checksum, and ErrSnapshotVersion if it was written by another version.
+func ReadSnapshot(r io.Reader, checksum [sha256.Size]byte) (*SLGraph, error) {
+	sr := &snapshotReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(sr.r, magic); err != nil || !bytes.Equal(magic, []byte(snapshotMagic)) {
+		return nil, fmt.Errorf("%w: not a graph snapshot", ErrSnapshotCorrupt)
+	}
+	if version := sr.uvarint(); sr.err == nil && version != SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, version)
+	}
+	var sum [sha256.Size]byte
+	if _, err := io.ReadFull(sr.r, sum[:]); err != nil {
+		sr.fail(err)
+	}
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	if sum != checksum {
+		return nil, ErrSnapshotStale
+	}
+
+	graph := New()
+	if name := sr.str(); name != "" {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, err
+		}
+		graph.location = loc
+	}
+
+	calendars := make([]*gtfs.Calendar, sr.count())
+	for i := range calendars {
+		c := &gtfs.Calendar{ServiceID: sr.str()}
+		for _, day := range []*gtfs.Bool{&c.Monday, &c.Tuesday, &c.Wednesday, &c.Thursday, &c.Friday, &c.Saturday, &c.Sunday} {
+			*day = sr.flag()
+		}
+		c.StartDate = sr.date()
+		c.EndDate = sr.date()
+		calendars[i] = c
+	}
+	var calendarDates []*gtfs.CalendarDate
+	for range sr.count() {
+		serviceID := sr.str()
+		for range sr.count() {
+			date := sr.date()
+			calendarDates = append(calendarDates, &gtfs.CalendarDate{ServiceID: serviceID, Date: date, ExceptionType: gtfs.ExceptionType(sr.int())})
+		}
+	}
+	graph.calendar = NewServiceCalendar(calendars, calendarDates)
+
+	transfers := make([]*gtfs.Transfer, sr.count())
+	for i := range transfers {
+		transfers[i] = &gtfs.Transfer{FromStopID: sr.str(), ToStopID: sr.str(), TransferType: gtfs.TransferType(sr.int()), MinTransferTime: gtfs.Int(sr.int())}
+	}
+	graph.transfers = NewTransferRules(transfers)
+
+	stations := make([]*Stop, sr.count())
+	for i := range stations {
+		stations[i] = &Stop{
+			StopID:        sr.str(),
+			StopName:      sr.str(),
+			StopLatitude:  sr.str(),
+			StopLongitude: sr.str(),
+			LocationType:  "1",
+		}
+	}
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	graph.SetParentStations(stations)
+
+	vertices := make([]*Vertex, sr.count())
+	for i := range vertices {
+		v := NewVertex(sr.str())
+		stop := &Stop{
+			StopID:        sr.str(),
+			StopName:      sr.str(),
+			StopLatitude:  sr.str(),
+			StopLongitude: sr.str(),
+			LocationType:  sr.str(),
+			ParentStation: sr.str(),
+		}
+		if sr.err != nil {
+			return nil, sr.err
+		}
+		stop.StopNameLower = strings.ToLower(stop.StopName)
+		// stops without valid coordinates were logged when the feed was loaded
+		_ = v.SetMetadata(stop)
+		graph.AddVertex(v)
+		vertices[i] = v
+	}
+
+	infos := make([]*TripInfo, sr.count())
+	for i := range infos {
+		infos[i] = &TripInfo{
+			RouteID:        sr.str(),
+			RouteShortName: sr.str(),
+			RouteLongName:  sr.str(),
+			TripHeadsign:   sr.str(),
+			TripShortName:  sr.str(),
+			AgencyID:       sr.str(),
+			AgencyName:     sr.str(),
+		}
+	}
+
+	vertex := func() *Vertex {
+		i := sr.uvarint()
+		if sr.err == nil && i >= uint64(len(vertices)) {
+			sr.fail(fmt.Errorf("%w: vertex %d", ErrSnapshotCorrupt, i))
+		}
+		if sr.err != nil {
+			return nil
+		}
+		return vertices[i]
+	}
+	for range sr.count() {
+		from, to := vertex(), vertex()
+		m := EdgeProperties{
+			TripID:       sr.str(),
+			ServiceID:    sr.str(),
+			Departure:    sr.int(),
+			Arrival:      sr.int(),
+			TransferType: EdgeType(sr.uvarint()),
+			Mode:         Mode(sr.uvarint()),
+			Distance:     sr.float(),
+			Headway:      sr.int(),
+			StopSequence: sr.int(),
+		}
+		if info := sr.uvarint(); info > 0 {
+			if info > uint64(len(infos)) {
+				sr.fail(fmt.Errorf("%w: trip info %d", ErrSnapshotCorrupt, info))
+			} else {
+				m.TripInfo = infos[info-1]
+			}
+		}
+		if sr.err != nil {
+			return nil, sr.err
+		}
+		m.SourceStopName = from.metadata.StopName
+		m.DestStopName = to.metadata.StopName
+		if _, err := graph.AddEdge(from, to, m); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+		}
+	}
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	return graph, nil
+}
+
+// SaveSnapshot writes the graph to a snapshot file. The file is replaced
+// atomically, a reader never sees a half written snapshot.
+func (graph *SLGraph) SaveSnapshot(path string, checksum [sha256.Size]byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := graph.WriteSnapshot(tmp, checksum); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot streams a graph from a snapshot file, see ReadSnapshot
+func LoadSnapshot(path string, checksum [sha256.Size]byte) (*SLGraph, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadSnapshot(file, checksum)
+}
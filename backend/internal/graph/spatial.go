@@ -0,0 +1,122 @@
+package graph
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	EARTH_RADIUS = 6378100.0 // meters
+
+	metersPerDegreeLat = EARTH_RADIUS * math.Pi / 180
+	// gridCellMeters is the height of a grid cell, around the longest walking edge
+	gridCellMeters = MAX_WALK_DISTANCE
+)
+
+// NearbyStop is a stop found by a radius query together with its distance
+type NearbyStop struct {
+	Vertex   *Vertex `json:"-"`
+	Stop     *Stop   `json:"stop"`
+	Distance float64 `json:"distance"` // meters
+}
+
+type gridCell struct {
+	lat, lon int
+}
+
+// StopIndex is a uniform grid over the stop coordinates, used to find the
+// stops within a radius without comparing against every stop. Cells are
+// squares in degrees, so they get narrower in meters the further north.
+type StopIndex struct {
+	cellDegrees float64
+	cells       map[gridCell][]*Vertex
+	size        int
+}
+
+// NewStopIndex creates an empty index
+func NewStopIndex() *StopIndex {
+	return &StopIndex{
+		cellDegrees: gridCellMeters / metersPerDegreeLat,
+		cells:       make(map[gridCell][]*Vertex),
+	}
+}
+
+func (idx *StopIndex) cellOf(lat, lon float64) gridCell {
+	return gridCell{
+		lat: int(math.Floor(lat / idx.cellDegrees)),
+		lon: int(math.Floor(lon / idx.cellDegrees)),
+	}
+}
+
+// Add indexes a vertex by its stop coordinates, vertices without coordinates are ignored
+func (idx *StopIndex) Add(v *Vertex) {
+	if v == nil || !v.metadata.HasCoordinates() {
+		return
+	}
+	cell := idx.cellOf(v.metadata.Lat, v.metadata.Lon)
+	idx.cells[cell] = append(idx.cells[cell], v)
+	idx.size++
+}
+
+// Remove removes a vertex from the index
+func (idx *StopIndex) Remove(v *Vertex) {
+	if v == nil || !v.metadata.HasCoordinates() {
+		return
+	}
+	cell := idx.cellOf(v.metadata.Lat, v.metadata.Lon)
+	vertices := idx.cells[cell]
+	for i, other := range vertices {
+		if other == v {
+			idx.cells[cell] = append(vertices[:i], vertices[i+1:]...)
+			idx.size--
+			break
+		}
+	}
+	if len(idx.cells[cell]) == 0 {
+		delete(idx.cells, cell)
+	}
+}
+
+// Len returns the number of indexed vertices
+func (idx *StopIndex) Len() int {
+	return idx.size
+}
+
+// Within returns the stops within radius meters of a coordinate, closest first
+func (idx *StopIndex) Within(lat, lon, radius float64) []NearbyStop {
+	dLat := radius / metersPerDegreeLat
+	// a degree of longitude shrinks towards the poles
+	cosLat := math.Cos(lat * math.Pi / 180)
+	dLon := 360.0
+	if cosLat > 1e-9 {
+		dLon = math.Min(dLat/cosLat, 360)
+	}
+	minCell := idx.cellOf(lat-dLat, lon-dLon)
+	maxCell := idx.cellOf(lat+dLat, lon+dLon)
+
+	var nearby []NearbyStop
+	for cLat := minCell.lat; cLat <= maxCell.lat; cLat++ {
+		for cLon := minCell.lon; cLon <= maxCell.lon; cLon++ {
+			for _, v := range idx.cells[gridCell{lat: cLat, lon: cLon}] {
+				dist := DistanceMeters(lat, lon, v.metadata.Lat, v.metadata.Lon)
+				if dist <= radius {
+					nearby = append(nearby, NearbyStop{Vertex: v, Stop: v.metadata, Distance: dist})
+				}
+			}
+		}
+	}
+	sort.Slice(nearby, func(i, j int) bool {
+		if nearby[i].Distance != nearby[j].Distance {
+			return nearby[i].Distance < nearby[j].Distance
+		}
+		return nearby[i].Vertex.label < nearby[j].Vertex.label
+	})
+	return nearby
+}
+
+// StopsWithinRadius returns the stops within radius meters of a coordinate, closest first
+func (graph *SLGraph) StopsWithinRadius(lat, lon, radius float64) []NearbyStop {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	return graph.stopIndex.Within(lat, lon, radius)
+}
@@ -0,0 +1,213 @@
+package graph
+
+import (
+	"sort"
+
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// STATION_RADIUS is how far apart stops with the same name may be and still be
+// platforms of one station, in meters. Only used when the feed has no parent
+// stations.
+const STATION_RADIUS = 500
+
+// STATION_ID_PREFIX starts the IDs of the stations grouped by name, feed
+// stations keep their stop ID
+const STATION_ID_PREFIX = "station:"
+
+// Station is what a rider thinks of as one stop: the platforms sharing a
+// name, like the metro, bus and ferry stops of Slussen. Routing from or to a
+// station may use any of its platforms.
+type Station struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+	Platforms []*Stop `json:"platforms"` // busiest first
+	platforms []*Vertex
+	// departures from all its platforms, how important it is
+	importance int
+}
+
+// Vertices returns the platforms of the station as vertices, busiest first
+func (s *Station) Vertices() []*Vertex {
+	return s.platforms
+}
+
+// locationType returns what a row of stops.txt describes, the SL feed writes
+// null for its stops
+func locationType(stop *Stop) gtfs.LocationType {
+	var t gtfs.LocationType
+	if err := t.UnmarshalCSV(stop.LocationType); err != nil {
+		return gtfs.LOCATION_STOP
+	}
+	return t
+}
+
+// stationIndex groups the stops of the graph into stations and finds them by
+// name. It's built from the graph on first use, and thrown away when the
+// graph changes.
+type stationIndex struct {
+	stations map[string]*Station
+	byStop   map[string]*Station // platform label -> its station
+	names    *nameIndex
+}
+
+func newStationIndex(vertices map[string]*Vertex, parents map[string]*Stop) *stationIndex {
+	idx := &stationIndex{
+		stations: make(map[string]*Station),
+		byStop:   make(map[string]*Station),
+	}
+	importance := make(map[*Vertex]int, len(vertices))
+	byParent := make(map[string][]*Vertex)
+	byName := make(map[string][]*Vertex)
+	for _, v := range vertices {
+		if v.metadata == nil {
+			continue
+		}
+		importance[v] = departures(v)
+		if parent := v.metadata.ParentStation; parent != "" && parents[parent] != nil {
+			byParent[parent] = append(byParent[parent], v)
+			continue
+		}
+		name := foldName(v.metadata.StopName)
+		byName[name] = append(byName[name], v)
+	}
+	var stations []*Station
+	for id, platforms := range byParent {
+		parent := parents[id]
+		s := &Station{ID: id, Name: parent.StopName, platforms: platforms}
+		if parent.HasCoordinates() {
+			s.Lat, s.Lon = parent.Lat, parent.Lon
+		} else {
+			s.Lat, s.Lon = center(platforms)
+		}
+		stations = append(stations, s)
+	}
+	for _, platforms := range byName {
+		for _, group := range groupNearby(platforms) {
+			s := &Station{ID: group[0].label, Name: group[0].metadata.StopName, platforms: group}
+			if len(group) > 1 {
+				s.ID = STATION_ID_PREFIX + group[0].label
+			}
+			s.Lat, s.Lon = center(group)
+			stations = append(stations, s)
+		}
+	}
+	for _, s := range stations {
+		sort.Slice(s.platforms, func(i, j int) bool {
+			a, b := s.platforms[i], s.platforms[j]
+			if importance[a] != importance[b] {
+				return importance[a] > importance[b]
+			}
+			return a.label < b.label
+		})
+		for _, v := range s.platforms {
+			s.Platforms = append(s.Platforms, v.metadata)
+			s.importance += importance[v]
+			idx.byStop[v.label] = s
+		}
+		idx.stations[s.ID] = s
+	}
+	idx.names = newNameIndex(stations)
+	return idx
+}
+
+// groupNearby splits stops sharing a name into groups within STATION_RADIUS
+// of the first stop of the group, by stop ID. Stops without coordinates are
+// grouped by themselves.
+func groupNearby(stops []*Vertex) [][]*Vertex {
+	sort.Slice(stops, func(i, j int) bool {
+		return stops[i].label < stops[j].label
+	})
+	var groups [][]*Vertex
+	var unlocated []*Vertex
+	for _, v := range stops {
+		if !v.metadata.HasCoordinates() {
+			unlocated = append(unlocated, v)
+			continue
+		}
+		joined := false
+		for i, group := range groups {
+			first := group[0].metadata
+			if DistanceMeters(first.Lat, first.Lon, v.metadata.Lat, v.metadata.Lon) <= STATION_RADIUS {
+				groups[i] = append(group, v)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			groups = append(groups, []*Vertex{v})
+		}
+	}
+	if len(unlocated) > 0 {
+		groups = append(groups, unlocated)
+	}
+	return groups
+}
+
+// center returns the mean coordinate of the stops with coordinates
+func center(stops []*Vertex) (float64, float64) {
+	var lat, lon float64
+	n := 0
+	for _, v := range stops {
+		if v.metadata.HasCoordinates() {
+			lat += v.metadata.Lat
+			lon += v.metadata.Lon
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return lat / float64(n), lon / float64(n)
+}
+
+// stationIndex returns the station index, building it if the graph changed
+// since it was last used. The caller holds the read lock, so the graph can't
+// change while it's built.
+func (graph *SLGraph) stationIndex() *stationIndex {
+	if idx := graph.stations.Load(); idx != nil {
+		return idx
+	}
+	graph.stationsMu.Lock()
+	defer graph.stationsMu.Unlock()
+	if idx := graph.stations.Load(); idx != nil {
+		return idx
+	}
+	idx := newStationIndex(graph.vertices, graph.parentStations)
+	graph.stations.Store(idx)
+	return idx
+}
+
+// GetStation returns a station by ID, nil if there is none
+func (graph *SLGraph) GetStation(id string) *Station {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	return graph.stationIndex().stations[id]
+}
+
+// StationOf returns the station a stop is a platform of
+func (graph *SLGraph) StationOf(v *Vertex) *Station {
+	if v == nil {
+		return nil
+	}
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	return graph.stationIndex().byStop[v.label]
+}
+
+// SetParentStations sets the stations of the feed, stops with location type
+// 1 that platforms refer to by parent_station
+func (graph *SLGraph) SetParentStations(stations []*Stop) {
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+	graph.parentStations = make(map[string]*Stop, len(stations))
+	for _, s := range stations {
+		if !s.located {
+			s.ParseCoordinates()
+		}
+		graph.parentStations[s.StopID] = s
+	}
+	graph.stations.Store(nil)
+}
@@ -0,0 +1,346 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// how well a name matches a query, lower is better
+const (
+	MATCH_EXACT     = iota // the whole name
+	MATCH_PREFIX           // every query word starts a word of the name
+	MATCH_SUBSTRING        // the query is somewhere in the name
+	MATCH_FUZZY            // every query word is close to a word of the name
+)
+
+// letters folded to their base letter, å and ä are a and ö is o
+var diacritics = map[rune]rune{
+	'å': 'a', 'ä': 'a', 'à': 'a', 'á': 'a', 'â': 'a', 'æ': 'a',
+	'ö': 'o', 'ø': 'o', 'ó': 'o', 'ò': 'o', 'ô': 'o',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'ü': 'u', 'ú': 'u', 'ù': 'u', 'û': 'u',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ç': 'c', 'ñ': 'n', 'ÿ': 'y',
+}
+
+// foldName normalizes a name for searching: lower case, diacritics folded and
+// anything but letters and digits turned into single spaces, so "T-Centralen"
+// and "t centralen" are the same
+func foldName(name string) string {
+	var b strings.Builder
+	space := true
+	for _, r := range strings.ToLower(name) {
+		if folded, ok := diacritics[r]; ok {
+			r = folded
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if !space {
+				b.WriteRune(' ')
+			}
+			space = true
+			continue
+		}
+		b.WriteRune(r)
+		space = false
+	}
+	return strings.TrimSuffix(b.String(), " ")
+}
+
+// typoBudget is the number of edits a query word of the length may have
+func typoBudget(word string) int {
+	switch n := len([]rune(word)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// indexedName is the name of a station
+type indexedName struct {
+	folded  string
+	words   []string
+	station *Station
+}
+
+type indexedWord struct {
+	word string
+	name int32
+}
+
+// nameIndex finds stations by name. Names are indexed by the trigrams of their
+// words, and the words are also kept sorted to look up queries too short for
+// trigrams.
+type nameIndex struct {
+	names []*indexedName
+	grams map[string][]int32 // trigram -> names with it
+	words []indexedWord      // sorted by word
+}
+
+// departures counts the rides leaving a stop, how important it is
+func departures(v *Vertex) int {
+	n := 0
+	for _, e := range v.edges {
+		if e.Metadata.TransferType == COMMUTE_EDGE {
+			n++
+		}
+	}
+	return n
+}
+
+// wordGrams returns the trigrams of a word, padded at the start, and at the
+// end unless the word may continue
+func wordGrams(word string, complete bool) []string {
+	padded := []rune(" " + word)
+	if complete {
+		padded = append(padded, ' ')
+	}
+	var grams []string
+	for i := 0; i+3 <= len(padded); i++ {
+		grams = append(grams, string(padded[i:i+3]))
+	}
+	return grams
+}
+
+func newNameIndex(stations []*Station) *nameIndex {
+	idx := &nameIndex{grams: make(map[string][]int32)}
+	for _, station := range stations {
+		folded := foldName(station.Name)
+		if folded == "" {
+			continue
+		}
+		idx.names = append(idx.names, &indexedName{folded: folded, words: strings.Fields(folded), station: station})
+	}
+	// sorted so the results don't depend on map order
+	sort.Slice(idx.names, func(i, j int) bool {
+		a, b := idx.names[i], idx.names[j]
+		if a.folded != b.folded {
+			return a.folded < b.folded
+		}
+		return a.station.ID < b.station.ID
+	})
+	for i, name := range idx.names {
+		seen := make(map[string]bool)
+		for _, word := range name.words {
+			idx.words = append(idx.words, indexedWord{word: word, name: int32(i)})
+			for _, gram := range wordGrams(word, true) {
+				if !seen[gram] {
+					seen[gram] = true
+					idx.grams[gram] = append(idx.grams[gram], int32(i))
+				}
+			}
+		}
+	}
+	sort.Slice(idx.words, func(i, j int) bool {
+		return idx.words[i].word < idx.words[j].word
+	})
+	return idx
+}
+
+// candidates returns the names that may match the query words. Every edit
+// changes at most three trigrams, so a name within the typo budget shares all
+// but that many trigrams with the query, and one less per word when the
+// query is in the middle of a word.
+func (idx *nameIndex) candidates(words []string) []int32 {
+	counts := make(map[int32]int)
+	queryGrams, budget := 0, 0
+	for _, word := range words {
+		budget += typoBudget(word)
+		seen := make(map[string]bool)
+		for _, gram := range wordGrams(word, false) {
+			if seen[gram] {
+				continue
+			}
+			seen[gram] = true
+			queryGrams++
+			for _, name := range idx.grams[gram] {
+				counts[name]++
+			}
+		}
+	}
+	if queryGrams == 0 {
+		// a single letter, every name with a word starting with it
+		first := words[0]
+		i := sort.Search(len(idx.words), func(i int) bool {
+			return idx.words[i].word >= first
+		})
+		var names []int32
+		for ; i < len(idx.words) && strings.HasPrefix(idx.words[i].word, first); i++ {
+			names = append(names, idx.words[i].name)
+		}
+		return names
+	}
+	needed := max(queryGrams-3*budget-len(words), 1)
+	var names []int32
+	for name, count := range counts {
+		if count >= needed {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// osa returns the optimal string alignment distance between a and b, and the
+// least distance between a and a prefix of b
+func osa(a, b []rune) (int, int) {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	prefix := d[len(a)][0]
+	for _, distance := range d[len(a)] {
+		prefix = min(prefix, distance)
+	}
+	return d[len(a)][len(b)], prefix
+}
+
+// match rates a name against a query, returning how it matches and for fuzzy
+// matches the number of edits
+func (name *indexedName) match(query string, words []string) (int, int, bool) {
+	if name.folded == query {
+		return MATCH_EXACT, 0, true
+	}
+	prefixes := true
+	for _, word := range words {
+		found := false
+		for _, nameWord := range name.words {
+			if strings.HasPrefix(nameWord, word) {
+				found = true
+				break
+			}
+		}
+		prefixes = prefixes && found
+	}
+	if prefixes {
+		return MATCH_PREFIX, 0, true
+	}
+	if strings.Contains(name.folded, query) {
+		return MATCH_SUBSTRING, 0, true
+	}
+	edits := 0
+	for i, word := range words {
+		budget := typoBudget(word)
+		best := budget + 1
+		for _, nameWord := range name.words {
+			distance, prefix := osa([]rune(word), []rune(nameWord))
+			if i == len(words)-1 {
+				// the last word may be cut short while typing
+				distance = prefix
+			}
+			best = min(best, distance)
+		}
+		if best > budget {
+			return 0, 0, false
+		}
+		edits += best
+	}
+	return MATCH_FUZZY, edits, true
+}
+
+// search returns the stations whose name matches the query, best first.
+// Names matching whole come first, then names with words starting with every
+// query word, names containing the query and names only close to it. Among
+// equal matches the station with the most departures wins.
+func (idx *nameIndex) search(query string) []*Station {
+	query = foldName(query)
+	if query == "" {
+		return nil
+	}
+	words := strings.Fields(query)
+
+	type hit struct {
+		name    *indexedName
+		quality int
+		edits   int
+	}
+	var hits []hit
+	seen := make(map[int32]bool)
+	for _, i := range idx.candidates(words) {
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+		name := idx.names[i]
+		if quality, edits, ok := name.match(query, words); ok {
+			hits = append(hits, hit{name, quality, edits})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.quality != b.quality {
+			return a.quality < b.quality
+		}
+		if a.edits != b.edits {
+			return a.edits < b.edits
+		}
+		if a.name.station.importance != b.name.station.importance {
+			return a.name.station.importance > b.name.station.importance
+		}
+		if a.name.folded != b.name.folded {
+			return a.name.folded < b.name.folded
+		}
+		return a.name.station.ID < b.name.station.ID
+	})
+	stations := make([]*Station, len(hits))
+	for i, h := range hits {
+		stations[i] = h.name.station
+	}
+	return stations
+}
+
+// SearchStations returns the stations whose name matches the query, best
+// first, at most limit of them or all with a limit of 0. Case, diacritics and
+// punctuation don't matter, and words may have typos.
+func (graph *SLGraph) SearchStations(query string, limit int) []*Station {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	stations := graph.stationIndex().names.search(query)
+	if limit > 0 && len(stations) > limit {
+		stations = stations[:limit]
+	}
+	if stations == nil {
+		stations = []*Station{}
+	}
+	return stations
+}
+
+// SearchStops is SearchStations returning the platforms of the stations,
+// busiest platform of each station first
+func (graph *SLGraph) SearchStops(query string, limit int) []*Vertex {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+	stops := []*Vertex{}
+	for _, station := range graph.stationIndex().names.search(query) {
+		for _, v := range station.platforms {
+			if limit > 0 && len(stops) == limit {
+				return stops
+			}
+			stops = append(stops, v)
+		}
+	}
+	return stops
+}
+
+// FindStopsByName returns every stop matching the name, best first, see SearchStops
+func (graph *SLGraph) FindStopsByName(name string) []*Vertex {
+	return graph.SearchStops(name, 0)
+}
@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"time"
+)
+
+const SECONDS_PER_DAY = 24 * 60 * 60
+
+// serviceDayLookahead is how many service days after the one a departure is
+// searched on that are considered, so a late search can continue onto the first
+// trips the next morning even if the service doesn't run that day.
+const serviceDayLookahead = 2
+
+// ServiceDayStart returns the start of the GTFS service day of a date. The GTFS
+// spec measures times from "noon minus 12h", which equals midnight except on
+// days with a daylight saving time change.
+func ServiceDayStart(date time.Time) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, 12, 0, 0, 0, date.Location()).Add(-12 * time.Hour)
+}
+
+// clock maps between absolute times and the integer seconds used during a
+// search. Search times are seconds since the start of the service day the
+// search departs on (day 0), so times of the previous and following service
+// days are negative or larger than SECONDS_PER_DAY.
+type clock struct {
+	origin   time.Time
+	calendar *ServiceCalendar
+	offsets  map[int]int             // day -> start of service day in search seconds
+	services map[int]map[string]bool // day -> active services
+}
+
+func newClock(t time.Time, calendar *ServiceCalendar) *clock {
+	return &clock{
+		origin:   ServiceDayStart(t),
+		calendar: calendar,
+		offsets:  make(map[int]int),
+		services: make(map[int]map[string]bool),
+	}
+}
+
+// Seconds converts an absolute time to search seconds
+func (c *clock) Seconds(t time.Time) int {
+	return int(t.Sub(c.origin) / time.Second)
+}
+
+// Time converts search seconds to an absolute time
+func (c *clock) Time(seconds int) time.Time {
+	return c.origin.Add(time.Duration(seconds) * time.Second)
+}
+
+// dayOffset returns the start of a service day relative to day 0 in search seconds
+func (c *clock) dayOffset(day int) int {
+	if offset, ok := c.offsets[day]; ok {
+		return offset
+	}
+	offset := c.Seconds(ServiceDayStart(c.origin.AddDate(0, 0, day)))
+	c.offsets[day] = offset
+	return offset
+}
+
+// runs reports whether a service is active on a service day
+func (c *clock) runs(day int, serviceID string) bool {
+	if c.calendar.IsEmpty() {
+		return true
+	}
+	active, ok := c.services[day]
+	if !ok {
+		active = c.calendar.ServicesOn(c.origin.AddDate(0, 0, day))
+		c.services[day] = active
+	}
+	return active[serviceID]
+}
@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"log"
+
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// TransferRules holds the transfers between stops declared by the feed
+type TransferRules struct {
+	rules map[string]map[string]*gtfs.Transfer // from stop -> to stop -> transfer
+}
+
+// NewTransferRules creates the rules from the parsed transfers.txt, which may be
+// empty. Rules only apply between stops, rules for a route or trip are left out
+// rather than applied to every vehicle at the stops.
+func NewTransferRules(transfers []*gtfs.Transfer) *TransferRules {
+	tr := &TransferRules{rules: make(map[string]map[string]*gtfs.Transfer)}
+	for _, t := range transfers {
+		if t.FromRouteID != "" || t.ToRouteID != "" || t.FromTripID != "" || t.ToTripID != "" {
+			log.Printf("Skipping transfer from %s to %s, transfers of a route or trip aren't supported", t.FromStopID, t.ToStopID)
+			continue
+		}
+		if _, ok := tr.rules[t.FromStopID]; !ok {
+			tr.rules[t.FromStopID] = make(map[string]*gtfs.Transfer)
+		}
+		tr.rules[t.FromStopID][t.ToStopID] = t
+	}
+	return tr
+}
+
+// Rule returns the transfer declared between two stops, nil if there is none.
+// A rule from a stop to itself applies to changing vehicle at that stop.
+func (tr *TransferRules) Rule(fromStopID, toStopID string) *gtfs.Transfer {
+	if tr == nil {
+		return nil
+	}
+	return tr.rules[fromStopID][toStopID]
+}
+
+// All returns every declared transfer
+func (tr *TransferRules) All() []*gtfs.Transfer {
+	var all []*gtfs.Transfer
+	if tr == nil {
+		return all
+	}
+	for _, toMap := range tr.rules {
+		for _, t := range toMap {
+			all = append(all, t)
+		}
+	}
+	return all
+}
+
+// minChangeTime returns the seconds needed to change vehicle at a stop, the
+// profile's minimum unless the feed says otherwise, and false if changing
+// vehicle there isn't possible
+func (ctx *searchContext) minChangeTime(stopID string) (int, bool) {
+	rule := ctx.transfers.Rule(stopID, stopID)
+	if rule == nil {
+		return ctx.profile.MinTransferTime, true
+	}
+	switch rule.TransferType {
+	case gtfs.TRANSFER_NOT_POSSIBLE:
+		return 0, false
+	case gtfs.TRANSFER_TIMED:
+		// the departing vehicle waits for the arriving one
+		return 0, true
+	case gtfs.TRANSFER_MIN_TIME:
+		return max(int(rule.MinTransferTime), ctx.profile.MinTransferTime), true
+	}
+	return ctx.profile.MinTransferTime, true
+}
+
+// walkTime returns the seconds needed to walk a walking edge, at least the
+// minimum transfer time declared by the feed, and false if the transfer isn't possible
+func (ctx *searchContext) walkTime(e *Edge) (int, bool) {
+	walk := e.walkSeconds(ctx.profile)
+	rule := ctx.transfers.Rule(e.source.label, e.dest.label)
+	if rule == nil {
+		return walk, true
+	}
+	switch rule.TransferType {
+	case gtfs.TRANSFER_NOT_POSSIBLE:
+		return 0, false
+	case gtfs.TRANSFER_MIN_TIME:
+		return max(walk, int(rule.MinTransferTime)), true
+	}
+	return walk, true
+}
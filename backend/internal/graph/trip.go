@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// TripInfo is what a rider sees of a trip: the line, where it's heading and who
+// runs it. All edges of a trip share one TripInfo, walking edges have none.
+type TripInfo struct {
+	RouteID        string `json:"routeId"`
+	RouteShortName string `json:"routeShortName"` // the line number
+	RouteLongName  string `json:"routeLongName,omitempty"`
+	TripHeadsign   string `json:"tripHeadsign"`
+	TripShortName  string `json:"tripShortName,omitempty"`
+	AgencyID       string `json:"agencyId"`
+	AgencyName     string `json:"agencyName"`
+}
+
+// newTripInfo joins a trip with its route and agency, either may be nil
+func newTripInfo(trip *gtfs.Trip, route *gtfs.Route, agency *gtfs.Agency) *TripInfo {
+	info := &TripInfo{
+		RouteID:       trip.RouteID,
+		TripHeadsign:  feedValue(trip.TripHeadsign),
+		TripShortName: feedValue(trip.TripShortName),
+	}
+	if route != nil {
+		info.RouteShortName = feedValue(route.RouteShortName)
+		info.RouteLongName = feedValue(route.RouteLongName)
+		info.AgencyID = route.AgencyID
+	}
+	if agency != nil {
+		info.AgencyID = agency.AgencyID
+		info.AgencyName = agency.AgencyName
+	}
+	return info
+}
+
+// feedValue cleans an optional text value, the SL feed writes null for missing values
+func feedValue(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "null" {
+		return ""
+	}
+	return value
+}
+
+// tripInfos creates the TripInfo of every trip, keyed by trip ID. Routes
+// without an agency_id belong to the only agency of the feed.
+func tripInfos(agencies []*gtfs.Agency, routes []*gtfs.Route, trips []*gtfs.Trip) map[string]*TripInfo {
+	agenciesByID := make(map[string]*gtfs.Agency)
+	for _, agency := range agencies {
+		agenciesByID[agency.AgencyID] = agency
+	}
+	routesByID := make(map[string]*gtfs.Route)
+	for _, route := range routes {
+		routesByID[route.RouteID] = route
+	}
+	infos := make(map[string]*TripInfo, len(trips))
+	for _, trip := range trips {
+		route := routesByID[trip.RouteID]
+		var agency *gtfs.Agency
+		if route != nil {
+			agency = agenciesByID[route.AgencyID]
+		}
+		if agency == nil && len(agencies) == 1 {
+			agency = agencies[0]
+		}
+		infos[trip.TripID] = newTripInfo(trip, route, agency)
+	}
+	return infos
+}
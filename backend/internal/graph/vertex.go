@@ -6,10 +6,13 @@ import "sync/atomic"
 // Vertex and Edge definitions
 // ----------------------------
 
+// Vertex is a stop of the graph. Its edges are changed under the lock of the
+// graph, but the accessors of the vertex itself don't take it, they may only
+// be used while the graph isn't being changed. SLGraph.EdgesOf locks.
 type Vertex struct {
 	label    string
 	edges    []*Edge // outgoing edges
-	inDegree int
+	incoming []*Edge
 	metadata *Stop
 }
 
@@ -25,33 +28,53 @@ func (v *Vertex) Metadata() *Stop {
 	return v.metadata
 }
 
-func (v *Vertex) SetMetadata(data *Stop) {
+// SetMetadata sets the stop of the vertex, parsing its coordinates. The stop
+// is set even if its coordinates are invalid, it's just left out of the
+// spatial index. A graph indexes the stop when the vertex is added, so set it
+// before adding the vertex or use SLGraph.SetVertexMetadata.
+func (v *Vertex) SetMetadata(data *Stop) error {
 	v.metadata = data
+	if data != nil && !data.located {
+		return data.ParseCoordinates()
+	}
+	return nil
 }
 
 func (v *Vertex) AddEdge(edge *Edge) {
 	v.edges = append(v.edges, edge)
-	edge.dest.inDegree++
+	edge.dest.incoming = append(edge.dest.incoming, edge)
 }
 
+// OutDegree is the number of outgoing edges, not safe while the graph changes
 func (v *Vertex) OutDegree() int {
 	return len(v.edges)
 }
 
+// InDegree is the number of incoming edges, not safe while the graph changes
 func (v *Vertex) InDegree() int {
-	return v.inDegree
+	return len(v.incoming)
 }
 
+// Degree is the number of edges, not safe while the graph changes
 func (v *Vertex) Degree() int {
-	return v.inDegree + len(v.edges)
+	return len(v.incoming) + len(v.edges)
 }
 
+// Edges returns a copy of the outgoing edges, not safe while the graph changes
 func (v *Vertex) Edges() []*Edge {
 	copyEdges := make([]*Edge, len(v.edges))
 	copy(copyEdges, v.edges)
 	return copyEdges
 }
 
+// IncomingEdges returns a copy of the edges ending in the vertex, not safe
+// while the graph changes
+func (v *Vertex) IncomingEdges() []*Edge {
+	copyEdges := make([]*Edge, len(v.incoming))
+	copy(copyEdges, v.incoming)
+	return copyEdges
+}
+
 // ----------------------------
 // Vertex methods
 // ----------------------------
@@ -63,6 +86,12 @@ func (graph *SLGraph) AddVertexByLabel(label string) *Vertex {
 }
 
 func (graph *SLGraph) AddVertex(v *Vertex) {
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+	graph.addVertex(v)
+}
+
+func (graph *SLGraph) addVertex(v *Vertex) {
 	if v == nil {
 		return
 	}
@@ -70,46 +99,81 @@ func (graph *SLGraph) AddVertex(v *Vertex) {
 		return
 	}
 	graph.vertices[v.label] = v
+	graph.stopIndex.Add(v)
+	graph.changed()
 	atomic.AddUint32(&graph.verticesCount, 1)
 }
+
+// SetVertexMetadata sets the stop of a vertex already in the graph, moving it
+// in the spatial index
+func (graph *SLGraph) SetVertexMetadata(v *Vertex, data *Stop) error {
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+	if graph.vertices[v.label] != v {
+		return v.SetMetadata(data)
+	}
+	graph.stopIndex.Remove(v)
+	err := v.SetMetadata(data)
+	graph.stopIndex.Add(v)
+	graph.changed()
+	return err
+}
+
 func (graph *SLGraph) GetVertexByID(label string) *Vertex {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
 	return graph.vertices[label]
 }
 func (graph *SLGraph) ContainsVertex(v *Vertex) bool {
 	if v == nil {
 		return false
 	}
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
 	_, ok := graph.vertices[v.label]
 	return ok
 }
 
 func (graph *SLGraph) RemoveVertices(vertices ...*Vertex) {
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
 	for _, v := range vertices {
 		if v == nil || graph.vertices[v.label] == nil {
 			continue
 		}
 
 		// remove all edges where v is source
-		if destMap, ok := graph.edges[v.label]; ok {
-			for _, edge := range destMap {
-				edge.dest.inDegree--
+		for _, tripMap := range graph.edges[v.label] {
+			for _, edge := range tripMap {
+				edge.dest.incoming = removeEdgeFromSlice(edge.dest.incoming, edge)
+				atomic.AddUint32(&graph.edgesCount, ^(uint32(1) - 1))
 			}
-			delete(graph.edges, v.label)
 		}
+		delete(graph.edges, v.label)
+		v.edges = nil
 
 		// remove all edges where v is destination
-		for _, destMap := range graph.edges {
-			if edge, ok := destMap[v.label]; ok {
+		for srcLabel, destMap := range graph.edges {
+			for _, edge := range destMap[v.label] {
 				edge.source.edges = removeEdgeFromSlice(edge.source.edges, edge)
-				delete(destMap, v.label)
+				atomic.AddUint32(&graph.edgesCount, ^(uint32(1) - 1))
+			}
+			delete(destMap, v.label)
+			if len(destMap) == 0 {
+				delete(graph.edges, srcLabel)
 			}
 		}
+		v.incoming = nil
 
 		delete(graph.vertices, v.label)
+		graph.stopIndex.Remove(v)
+		graph.changed()
 		atomic.AddUint32(&graph.verticesCount, ^(uint32(1) - 1))
 	}
 }
 func (graph *SLGraph) GetAllVertices() []*Vertex {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
 	var out []*Vertex
 	for _, v := range graph.vertices {
 		out = append(out, v)
@@ -0,0 +1,215 @@
+// Package gtfs reads GTFS feeds from a directory or a .zip archive
+package gtfs
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocarina/gocsv"
+)
+
+// standard GTFS file names
+const (
+	FILE_AGENCY         = "agency.txt"
+	FILE_STOPS          = "stops.txt"
+	FILE_ROUTES         = "routes.txt"
+	FILE_TRIPS          = "trips.txt"
+	FILE_STOP_TIMES     = "stop_times.txt"
+	FILE_CALENDAR       = "calendar.txt"
+	FILE_CALENDAR_DATES = "calendar_dates.txt"
+	FILE_TRANSFERS      = "transfers.txt"
+)
+
+// ErrMissingFile is returned when a required file is not in the feed
+var ErrMissingFile = errors.New("gtfs: missing file")
+
+// Feed is an opened GTFS feed, files are read on demand
+type Feed struct {
+	source string
+	fsys   fs.FS
+	closer io.Closer // the zip archive, nil for directories
+}
+
+// Load opens a GTFS feed from a directory or a .zip archive. Files may use the
+// standard names (stops.txt) or the names of the SL export (sl_stops.csv), and
+// a zip may keep the files in a single top level directory.
+func Load(source string) (*Feed, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	feed := &Feed{source: source}
+	if info.IsDir() {
+		feed.fsys = os.DirFS(source)
+		return feed, nil
+	}
+	archive, err := zip.OpenReader(source)
+	if err != nil {
+		return nil, fmt.Errorf("gtfs: %s is neither a directory nor a zip archive: %w", source, err)
+	}
+	feed.fsys, feed.closer = archive, archive
+	if !feed.Has(FILE_STOPS) {
+		// the feed may be zipped together with its directory
+		if dir, ok := singleDir(archive); ok {
+			feed.fsys, _ = fs.Sub(archive, dir)
+		}
+	}
+	return feed, nil
+}
+
+// singleDir returns the only top level directory of a file system
+func singleDir(fsys fs.FS) (string, bool) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", false
+	}
+	dir := ""
+	for _, entry := range entries {
+		if entry.Name() == "__MACOSX" {
+			continue
+		}
+		if !entry.IsDir() || dir != "" {
+			return "", false
+		}
+		dir = entry.Name()
+	}
+	return dir, dir != ""
+}
+
+// Source returns the path the feed was loaded from
+func (f *Feed) Source() string {
+	return f.source
+}
+
+// Close releases the archive of a zipped feed
+func (f *Feed) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
+
+// fileNames returns the names a file may have in the feed, standard name first
+func fileNames(name string) []string {
+	return []string{name, "sl_" + strings.TrimSuffix(name, path.Ext(name)) + ".csv"}
+}
+
+// Open opens a file of the feed by its standard name
+func (f *Feed) Open(name string) (fs.File, error) {
+	for _, candidate := range fileNames(name) {
+		file, err := f.fsys.Open(candidate)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return file, err
+	}
+	return nil, fmt.Errorf("%w %s in %s", ErrMissingFile, name, f.source)
+}
+
+// Has reports whether the feed contains a file
+func (f *Feed) Has(name string) bool {
+	for _, candidate := range fileNames(name) {
+		if _, err := fs.Stat(f.fsys, candidate); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Unmarshal reads a required CSV file into out, a pointer to a slice
+func (f *Feed) Unmarshal(name string, out any) error {
+	file, err := f.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := gocsv.Unmarshal(file, out); err != nil {
+		return fmt.Errorf("gtfs: %s: %w", name, err)
+	}
+	return nil
+}
+
+// UnmarshalOptional reads a CSV file into out, a missing file is not an error
+// and leaves out untouched
+func (f *Feed) UnmarshalOptional(name string, out any) error {
+	if !f.Has(name) {
+		return nil
+	}
+	return f.Unmarshal(name, out)
+}
+
+// Checksum is a SHA-256 over the names and contents of the CSV files of the
+// feed, it changes whenever the feed does. Other files next to the feed, like
+// a graph snapshot, don't count.
+func (f *Feed) Checksum() ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	entries, err := fs.ReadDir(f.fsys, ".")
+	if err != nil {
+		return sum, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	h := sha256.New()
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if !entry.Type().IsRegular() || (ext != ".txt" && ext != ".csv") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return sum, err
+		}
+		// name and size first, so moving bytes between files changes the sum
+		io.WriteString(h, entry.Name())
+		binary.Write(h, binary.LittleEndian, info.Size())
+		file, err := f.fsys.Open(entry.Name())
+		if err != nil {
+			return sum, err
+		}
+		_, err = io.Copy(h, file)
+		file.Close()
+		if err != nil {
+			return sum, err
+		}
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// ModTime returns when the feed at source last changed: the latest
+// modification of the archive, or of the directory and the files in it
+func ModTime(source string) (time.Time, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return time.Time{}, err
+	}
+	latest := info.ModTime()
+	if !info.IsDir() {
+		return latest, nil
+	}
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,250 @@
+package gtfs
+
+// the remaining files of the spec, see feed.go for the ones the graph reads
+const (
+	FILE_FARE_ATTRIBUTES = "fare_attributes.txt"
+	FILE_FARE_RULES      = "fare_rules.txt"
+	FILE_SHAPES          = "shapes.txt"
+	FILE_FREQUENCIES     = "frequencies.txt"
+	FILE_PATHWAYS        = "pathways.txt"
+	FILE_LEVELS          = "levels.txt"
+	FILE_FEED_INFO       = "feed_info.txt"
+)
+
+// Agency is a row of agency.txt
+type Agency struct {
+	AgencyID       string `csv:"agency_id"`
+	AgencyName     string `csv:"agency_name"`
+	AgencyURL      string `csv:"agency_url"`
+	AgencyTimezone string `csv:"agency_timezone"`
+	AgencyLang     string `csv:"agency_lang"`
+	AgencyPhone    string `csv:"agency_phone"`
+	AgencyFareURL  string `csv:"agency_fare_url"`
+	AgencyEmail    string `csv:"agency_email"`
+}
+
+// Stop is a row of stops.txt, a stop, station, entrance, node or boarding area
+type Stop struct {
+	StopID             string        `csv:"stop_id"`
+	StopCode           string        `csv:"stop_code"`
+	StopName           string        `csv:"stop_name"`
+	TTSStopName        string        `csv:"tts_stop_name"`
+	StopDesc           string        `csv:"stop_desc"`
+	StopLat            Float         `csv:"stop_lat"`
+	StopLon            Float         `csv:"stop_lon"`
+	ZoneID             string        `csv:"zone_id"`
+	StopURL            string        `csv:"stop_url"`
+	LocationType       LocationType  `csv:"location_type"`
+	ParentStation      string        `csv:"parent_station"`
+	StopTimezone       string        `csv:"stop_timezone"`
+	WheelchairBoarding Accessibility `csv:"wheelchair_boarding"`
+	LevelID            string        `csv:"level_id"`
+	PlatformCode       string        `csv:"platform_code"`
+}
+
+// Route is a row of routes.txt
+type Route struct {
+	RouteID           string     `csv:"route_id"`
+	AgencyID          string     `csv:"agency_id"`
+	RouteShortName    string     `csv:"route_short_name"`
+	RouteLongName     string     `csv:"route_long_name"`
+	RouteDesc         string     `csv:"route_desc"`
+	RouteType         RouteType  `csv:"route_type"`
+	RouteURL          string     `csv:"route_url"`
+	RouteColor        string     `csv:"route_color"`
+	RouteTextColor    string     `csv:"route_text_color"`
+	RouteSortOrder    Int        `csv:"route_sort_order"`
+	ContinuousPickup  PickupType `csv:"continuous_pickup"`
+	ContinuousDropOff PickupType `csv:"continuous_drop_off"`
+}
+
+// Trip is a row of trips.txt
+type Trip struct {
+	RouteID              string        `csv:"route_id"`
+	ServiceID            string        `csv:"service_id"`
+	TripID               string        `csv:"trip_id"`
+	TripHeadsign         string        `csv:"trip_headsign"`
+	TripShortName        string        `csv:"trip_short_name"`
+	DirectionID          Int           `csv:"direction_id"`
+	BlockID              string        `csv:"block_id"`
+	ShapeID              string        `csv:"shape_id"`
+	WheelchairAccessible Accessibility `csv:"wheelchair_accessible"`
+	BikesAllowed         Accessibility `csv:"bikes_allowed"`
+}
+
+// StopTime is a row of stop_times.txt
+type StopTime struct {
+	TripID            string     `csv:"trip_id"`
+	ArrivalTime       Time       `csv:"arrival_time"`
+	DepartureTime     Time       `csv:"departure_time"`
+	StopID            string     `csv:"stop_id"`
+	StopSequence      int        `csv:"stop_sequence"`
+	StopHeadsign      string     `csv:"stop_headsign"`
+	PickupType        PickupType `csv:"pickup_type"`
+	DropOffType       PickupType `csv:"drop_off_type"`
+	ContinuousPickup  PickupType `csv:"continuous_pickup"`
+	ContinuousDropOff PickupType `csv:"continuous_drop_off"`
+	ShapeDistTraveled Float      `csv:"shape_dist_traveled"`
+	Timepoint         Bool       `csv:"timepoint"`
+}
+
+// Calendar is a row of calendar.txt, the weekly schedule of a service
+type Calendar struct {
+	ServiceID string `csv:"service_id"`
+	Monday    Bool   `csv:"monday"`
+	Tuesday   Bool   `csv:"tuesday"`
+	Wednesday Bool   `csv:"wednesday"`
+	Thursday  Bool   `csv:"thursday"`
+	Friday    Bool   `csv:"friday"`
+	Saturday  Bool   `csv:"saturday"`
+	Sunday    Bool   `csv:"sunday"`
+	StartDate Date   `csv:"start_date"`
+	EndDate   Date   `csv:"end_date"`
+}
+
+// CalendarDate is a row of calendar_dates.txt, an exception to the weekly schedule
+type CalendarDate struct {
+	ServiceID     string        `csv:"service_id"`
+	Date          Date          `csv:"date"`
+	ExceptionType ExceptionType `csv:"exception_type"`
+}
+
+// FareAttribute is a row of fare_attributes.txt
+type FareAttribute struct {
+	FareID           string `csv:"fare_id"`
+	Price            Float  `csv:"price"`
+	CurrencyType     string `csv:"currency_type"`
+	PaymentMethod    Int    `csv:"payment_method"`
+	Transfers        string `csv:"transfers"` // empty means unlimited
+	AgencyID         string `csv:"agency_id"`
+	TransferDuration Int    `csv:"transfer_duration"`
+}
+
+// FareRule is a row of fare_rules.txt
+type FareRule struct {
+	FareID        string `csv:"fare_id"`
+	RouteID       string `csv:"route_id"`
+	OriginID      string `csv:"origin_id"`
+	DestinationID string `csv:"destination_id"`
+	ContainsID    string `csv:"contains_id"`
+}
+
+// Shape is a row of shapes.txt, one point of the path a vehicle travels
+type Shape struct {
+	ShapeID           string `csv:"shape_id"`
+	ShapePtLat        Float  `csv:"shape_pt_lat"`
+	ShapePtLon        Float  `csv:"shape_pt_lon"`
+	ShapePtSequence   int    `csv:"shape_pt_sequence"`
+	ShapeDistTraveled Float  `csv:"shape_dist_traveled"`
+}
+
+// Frequency is a row of frequencies.txt, a trip repeated every headway
+type Frequency struct {
+	TripID      string     `csv:"trip_id"`
+	StartTime   Time       `csv:"start_time"`
+	EndTime     Time       `csv:"end_time"`
+	HeadwaySecs int        `csv:"headway_secs"`
+	ExactTimes  ExactTimes `csv:"exact_times"`
+}
+
+// Transfer is a row of transfers.txt
+type Transfer struct {
+	FromStopID      string       `csv:"from_stop_id"`
+	ToStopID        string       `csv:"to_stop_id"`
+	FromRouteID     string       `csv:"from_route_id"`
+	ToRouteID       string       `csv:"to_route_id"`
+	FromTripID      string       `csv:"from_trip_id"`
+	ToTripID        string       `csv:"to_trip_id"`
+	TransferType    TransferType `csv:"transfer_type"`
+	MinTransferTime Int          `csv:"min_transfer_time"`
+}
+
+// Pathway is a row of pathways.txt, a link between locations inside a station
+type Pathway struct {
+	PathwayID            string      `csv:"pathway_id"`
+	FromStopID           string      `csv:"from_stop_id"`
+	ToStopID             string      `csv:"to_stop_id"`
+	PathwayMode          PathwayMode `csv:"pathway_mode"`
+	IsBidirectional      Bool        `csv:"is_bidirectional"`
+	Length               Float       `csv:"length"`
+	TraversalTime        Int         `csv:"traversal_time"`
+	StairCount           Int         `csv:"stair_count"`
+	MaxSlope             Float       `csv:"max_slope"`
+	MinWidth             Float       `csv:"min_width"`
+	SignpostedAs         string      `csv:"signposted_as"`
+	ReversedSignpostedAs string      `csv:"reversed_signposted_as"`
+}
+
+// Level is a row of levels.txt, a floor of a station
+type Level struct {
+	LevelID    string `csv:"level_id"`
+	LevelIndex Float  `csv:"level_index"`
+	LevelName  string `csv:"level_name"`
+}
+
+// FeedInfo is the row of feed_info.txt
+type FeedInfo struct {
+	FeedPublisherName string `csv:"feed_publisher_name"`
+	FeedPublisherURL  string `csv:"feed_publisher_url"`
+	FeedLang          string `csv:"feed_lang"`
+	DefaultLang       string `csv:"default_lang"`
+	FeedStartDate     Date   `csv:"feed_start_date"`
+	FeedEndDate       Date   `csv:"feed_end_date"`
+	FeedVersion       string `csv:"feed_version"`
+	FeedContactEmail  string `csv:"feed_contact_email"`
+	FeedContactURL    string `csv:"feed_contact_url"`
+}
+
+// Schedule is every file of a feed, optional files that are missing are empty
+type Schedule struct {
+	Agencies       []*Agency
+	Stops          []*Stop
+	Routes         []*Route
+	Trips          []*Trip
+	StopTimes      []*StopTime
+	Calendars      []*Calendar
+	CalendarDates  []*CalendarDate
+	FareAttributes []*FareAttribute
+	FareRules      []*FareRule
+	Shapes         []*Shape
+	Frequencies    []*Frequency
+	Transfers      []*Transfer
+	Pathways       []*Pathway
+	Levels         []*Level
+	FeedInfo       []*FeedInfo
+}
+
+// ReadSchedule reads every file of the feed into typed rows
+func (f *Feed) ReadSchedule() (*Schedule, error) {
+	s := &Schedule{}
+	required := map[string]any{
+		FILE_AGENCY:     &s.Agencies,
+		FILE_STOPS:      &s.Stops,
+		FILE_ROUTES:     &s.Routes,
+		FILE_TRIPS:      &s.Trips,
+		FILE_STOP_TIMES: &s.StopTimes,
+	}
+	for name, out := range required {
+		if err := f.Unmarshal(name, out); err != nil {
+			return nil, err
+		}
+	}
+	optional := map[string]any{
+		FILE_CALENDAR:        &s.Calendars,
+		FILE_CALENDAR_DATES:  &s.CalendarDates,
+		FILE_FARE_ATTRIBUTES: &s.FareAttributes,
+		FILE_FARE_RULES:      &s.FareRules,
+		FILE_SHAPES:          &s.Shapes,
+		FILE_FREQUENCIES:     &s.Frequencies,
+		FILE_TRANSFERS:       &s.Transfers,
+		FILE_PATHWAYS:        &s.Pathways,
+		FILE_LEVELS:          &s.Levels,
+		FILE_FEED_INFO:       &s.FeedInfo,
+	}
+	for name, out := range optional {
+		if err := f.UnmarshalOptional(name, out); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
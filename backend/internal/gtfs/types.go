@@ -0,0 +1,342 @@
+package gtfs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// empty reports whether a CSV value is missing, some feeds (SL) write null
+func empty(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || value == "null"
+}
+
+// parseEnum parses an integer enum value, missing values are the zero value
+func parseEnum(value string) (int, error) {
+	if empty(value) {
+		return 0, nil
+	}
+	return strconv.Atoi(strings.TrimSpace(value))
+}
+
+// Time is a time of the service day in seconds since noon minus 12h, it may
+// exceed 24:00:00 for trips running past midnight
+type Time int
+
+// NO_TIME is the value of an empty time, allowed for stops that aren't timepoints
+const NO_TIME Time = -1
+
+// ParseTime parses a HH:MM:SS time, the hours may have one digit
+func ParseTime(value string) (Time, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return NO_TIME, fmt.Errorf("gtfs: invalid time %q", value)
+	}
+	var hms [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (i > 0 && (len(part) != 2 || n > 59)) {
+			return NO_TIME, fmt.Errorf("gtfs: invalid time %q", value)
+		}
+		hms[i] = n
+	}
+	return Time(hms[0]*3600 + hms[1]*60 + hms[2]), nil
+}
+
+// IsSet reports whether the time was given
+func (t Time) IsSet() bool {
+	return t != NO_TIME
+}
+
+func (t Time) String() string {
+	if !t.IsSet() {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", t/3600, t/60%60, t%60)
+}
+
+func (t *Time) UnmarshalCSV(value string) error {
+	if empty(value) {
+		*t = NO_TIME
+		return nil
+	}
+	parsed, err := ParseTime(value)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+func (t Time) MarshalCSV() (string, error) {
+	return t.String(), nil
+}
+
+// DATE_LAYOUT is the YYYYMMDD format of GTFS dates
+const DATE_LAYOUT = "20060102"
+
+// Date is a GTFS calendar date, the zero value is an empty date
+type Date struct {
+	time.Time
+}
+
+func (d *Date) UnmarshalCSV(value string) error {
+	if empty(value) {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := time.Parse(DATE_LAYOUT, strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("gtfs: invalid date %q", value)
+	}
+	*d = Date{parsed}
+	return nil
+}
+
+func (d Date) MarshalCSV() (string, error) {
+	if d.IsZero() {
+		return "", nil
+	}
+	return d.Format(DATE_LAYOUT), nil
+}
+
+// Float is a decimal number where an empty value is allowed
+type Float float64
+
+func (f *Float) UnmarshalCSV(value string) error {
+	if empty(value) {
+		*f = 0
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return fmt.Errorf("gtfs: invalid number %q", value)
+	}
+	*f = Float(parsed)
+	return nil
+}
+
+// Int is an integer where an empty value is allowed
+type Int int
+
+func (i *Int) UnmarshalCSV(value string) error {
+	n, err := parseEnum(value)
+	if err != nil {
+		return fmt.Errorf("gtfs: invalid integer %q", value)
+	}
+	*i = Int(n)
+	return nil
+}
+
+// RouteType is the kind of vehicle of a route, both basic and extended types
+type RouteType int
+
+// ROUTE TYPES, the basic ones, extended types are in the ranges of
+// https://developers.google.com/transit/gtfs/reference/extended-route-types
+const (
+	ROUTE_TYPE_TRAM        RouteType = 0
+	ROUTE_TYPE_SUBWAY      RouteType = 1
+	ROUTE_TYPE_RAIL        RouteType = 2
+	ROUTE_TYPE_BUS         RouteType = 3
+	ROUTE_TYPE_FERRY       RouteType = 4
+	ROUTE_TYPE_CABLE_TRAM  RouteType = 5
+	ROUTE_TYPE_AERIAL_LIFT RouteType = 6
+	ROUTE_TYPE_FUNICULAR   RouteType = 7
+	ROUTE_TYPE_TROLLEYBUS  RouteType = 11
+	ROUTE_TYPE_MONORAIL    RouteType = 12
+	// ROUTE_TYPE_UNKNOWN is used for missing or unparsable route types
+	ROUTE_TYPE_UNKNOWN RouteType = -1
+)
+
+// Basic maps an extended route type to the closest basic one
+func (t RouteType) Basic() RouteType {
+	switch {
+	case t >= 0 && t < 100:
+		return t
+	case t >= 100 && t < 200, t >= 300 && t < 400:
+		return ROUTE_TYPE_RAIL
+	case t >= 200 && t < 300, t >= 700 && t < 800:
+		return ROUTE_TYPE_BUS
+	case t >= 400 && t < 500:
+		return ROUTE_TYPE_SUBWAY
+	case t >= 800 && t < 900:
+		return ROUTE_TYPE_TROLLEYBUS
+	case t >= 900 && t < 1000:
+		return ROUTE_TYPE_TRAM
+	case t >= 1000 && t < 1100, t == 1200:
+		return ROUTE_TYPE_FERRY
+	case t >= 1300 && t < 1400:
+		return ROUTE_TYPE_AERIAL_LIFT
+	case t >= 1400 && t < 1500:
+		return ROUTE_TYPE_FUNICULAR
+	}
+	return ROUTE_TYPE_UNKNOWN
+}
+
+func (t *RouteType) UnmarshalCSV(value string) error {
+	if empty(value) {
+		*t = ROUTE_TYPE_UNKNOWN
+		return nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("gtfs: invalid route_type %q", value)
+	}
+	*t = RouteType(n)
+	return nil
+}
+
+// LocationType is what a row of stops.txt describes
+type LocationType int
+
+// LOCATION TYPES
+const (
+	LOCATION_STOP LocationType = iota
+	LOCATION_STATION
+	LOCATION_ENTRANCE
+	LOCATION_GENERIC_NODE
+	LOCATION_BOARDING_AREA
+)
+
+func (t *LocationType) UnmarshalCSV(value string) error {
+	n, err := parseEnum(value)
+	if err != nil || n < int(LOCATION_STOP) || n > int(LOCATION_BOARDING_AREA) {
+		return fmt.Errorf("gtfs: invalid location_type %q", value)
+	}
+	*t = LocationType(n)
+	return nil
+}
+
+// Accessibility is used for wheelchair_boarding, wheelchair_accessible and bikes_allowed
+type Accessibility int
+
+// ACCESSIBILITY
+const (
+	ACCESSIBILITY_UNKNOWN Accessibility = iota
+	ACCESSIBILITY_POSSIBLE
+	ACCESSIBILITY_NOT_POSSIBLE
+)
+
+func (a *Accessibility) UnmarshalCSV(value string) error {
+	n, err := parseEnum(value)
+	if err != nil || n < 0 || n > 2 {
+		return fmt.Errorf("gtfs: invalid accessibility %q", value)
+	}
+	*a = Accessibility(n)
+	return nil
+}
+
+// PickupType is used for pickup_type, drop_off_type and continuous pickup and drop off
+type PickupType int
+
+// PICKUP TYPES
+const (
+	PICKUP_REGULAR PickupType = iota
+	PICKUP_NONE
+	PICKUP_PHONE_AGENCY
+	PICKUP_COORDINATE_WITH_DRIVER
+)
+
+func (p *PickupType) UnmarshalCSV(value string) error {
+	n, err := parseEnum(value)
+	if err != nil || n < 0 || n > 3 {
+		return fmt.Errorf("gtfs: invalid pickup or drop off type %q", value)
+	}
+	*p = PickupType(n)
+	return nil
+}
+
+// ExceptionType tells whether a calendar date adds or removes a service
+type ExceptionType int
+
+// EXCEPTION TYPES
+const (
+	EXCEPTION_ADDED   ExceptionType = 1
+	EXCEPTION_REMOVED ExceptionType = 2
+)
+
+func (e *ExceptionType) UnmarshalCSV(value string) error {
+	n, err := parseEnum(value)
+	if err != nil || (n != 1 && n != 2) {
+		return fmt.Errorf("gtfs: invalid exception_type %q", value)
+	}
+	*e = ExceptionType(n)
+	return nil
+}
+
+// TransferType is the kind of connection between two stops in transfers.txt
+type TransferType int
+
+// TRANSFER TYPES
+const (
+	TRANSFER_RECOMMENDED TransferType = iota
+	TRANSFER_TIMED
+	TRANSFER_MIN_TIME
+	TRANSFER_NOT_POSSIBLE
+	TRANSFER_IN_SEAT
+	TRANSFER_IN_SEAT_NOT_ALLOWED
+)
+
+func (t *TransferType) UnmarshalCSV(value string) error {
+	n, err := parseEnum(value)
+	if err != nil || n < 0 || n > int(TRANSFER_IN_SEAT_NOT_ALLOWED) {
+		return fmt.Errorf("gtfs: invalid transfer_type %q", value)
+	}
+	*t = TransferType(n)
+	return nil
+}
+
+// ExactTimes tells whether a frequency based trip runs on a fixed schedule
+type ExactTimes int
+
+// EXACT TIMES
+const (
+	FREQUENCY_BASED ExactTimes = iota // headway only, trips don't have exact departures
+	SCHEDULE_BASED                    // trips depart exactly every headway from start_time
+)
+
+func (e *ExactTimes) UnmarshalCSV(value string) error {
+	n, err := parseEnum(value)
+	if err != nil || n < 0 || n > 1 {
+		return fmt.Errorf("gtfs: invalid exact_times %q", value)
+	}
+	*e = ExactTimes(n)
+	return nil
+}
+
+// PathwayMode is the kind of a pathway inside a station
+type PathwayMode int
+
+// PATHWAY MODES
+const (
+	PATHWAY_WALKWAY PathwayMode = iota + 1
+	PATHWAY_STAIRS
+	PATHWAY_MOVING_SIDEWALK
+	PATHWAY_ESCALATOR
+	PATHWAY_ELEVATOR
+	PATHWAY_FARE_GATE
+	PATHWAY_EXIT_GATE
+)
+
+func (m *PathwayMode) UnmarshalCSV(value string) error {
+	n, err := parseEnum(value)
+	if err != nil || n < int(PATHWAY_WALKWAY) || n > int(PATHWAY_EXIT_GATE) {
+		return fmt.Errorf("gtfs: invalid pathway_mode %q", value)
+	}
+	*m = PathwayMode(n)
+	return nil
+}
+
+// Bool is a 0 or 1 flag, empty is false
+type Bool bool
+
+func (b *Bool) UnmarshalCSV(value string) error {
+	n, err := parseEnum(value)
+	if err != nil || n < 0 || n > 1 {
+		return fmt.Errorf("gtfs: invalid flag %q", value)
+	}
+	*b = n == 1
+	return nil
+}
@@ -0,0 +1,236 @@
+package gtfs
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Problem is an error found in a feed by Validate
+type Problem struct {
+	File    string
+	Line    int // line of the row in the file, the header is line 1
+	Message string
+}
+
+func (p Problem) Error() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// line is the line number of the i:th row, assuming one line per row
+func line(i int) int {
+	return i + 2
+}
+
+// validator collects problems while checking a schedule
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) report(file string, i int, format string, args ...any) {
+	v.problems = append(v.problems, Problem{File: file, Line: line(i), Message: fmt.Sprintf(format, args...)})
+}
+
+// ids indexes the IDs of a file, reporting empty and duplicate ones
+func (v *validator) ids(file, field string, n int, id func(int) string) map[string]bool {
+	seen := make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		value := id(i)
+		switch {
+		case value == "":
+			v.report(file, i, "missing %s", field)
+		case seen[value]:
+			v.report(file, i, "duplicate %s %q", field, value)
+		}
+		seen[value] = true
+	}
+	return seen
+}
+
+// reference reports a row referring to an ID that doesn't exist, empty
+// references are allowed, required fields are checked by the caller
+func (v *validator) reference(file string, i int, field, value string, known map[string]bool) {
+	if value != "" && !known[value] {
+		v.report(file, i, "%s %q does not exist", field, value)
+	}
+}
+
+// Validate checks the referential integrity of the schedule, that every row
+// refers to existing stops, trips, routes, services and so on, and that trips
+// never go back in time. Rows the graph can't use are reported too. The
+// problems are ordered by file.
+func (s *Schedule) Validate() []Problem {
+	v := &validator{}
+
+	agencies := v.ids(FILE_AGENCY, "agency_id", len(s.Agencies), func(i int) string {
+		if len(s.Agencies) == 1 && s.Agencies[i].AgencyID == "" {
+			return "-" // the only agency may leave out its ID
+		}
+		return s.Agencies[i].AgencyID
+	})
+	levels := v.ids(FILE_LEVELS, "level_id", len(s.Levels), func(i int) string { return s.Levels[i].LevelID })
+	stops := v.ids(FILE_STOPS, "stop_id", len(s.Stops), func(i int) string { return s.Stops[i].StopID })
+	stations := make(map[string]bool)
+	for _, stop := range s.Stops {
+		if stop.LocationType == LOCATION_STATION {
+			stations[stop.StopID] = true
+		}
+	}
+	for i, stop := range s.Stops {
+		v.reference(FILE_STOPS, i, "level_id", stop.LevelID, levels)
+		switch {
+		case stop.ParentStation == "":
+			if stop.LocationType >= LOCATION_ENTRANCE {
+				v.report(FILE_STOPS, i, "location_type %d requires a parent_station", stop.LocationType)
+			}
+		case stop.LocationType == LOCATION_STATION:
+			v.report(FILE_STOPS, i, "station %q can't have a parent_station", stop.StopID)
+		case !stops[stop.ParentStation]:
+			v.report(FILE_STOPS, i, "parent_station %q does not exist", stop.ParentStation)
+		case stop.LocationType == LOCATION_STOP && !stations[stop.ParentStation]:
+			v.report(FILE_STOPS, i, "parent_station %q is not a station", stop.ParentStation)
+		}
+		if stop.LocationType <= LOCATION_ENTRANCE && (stop.StopLat < -90 || stop.StopLat > 90 || stop.StopLon < -180 || stop.StopLon > 180) {
+			v.report(FILE_STOPS, i, "coordinate %f, %f out of range", stop.StopLat, stop.StopLon)
+		}
+	}
+
+	routes := v.ids(FILE_ROUTES, "route_id", len(s.Routes), func(i int) string { return s.Routes[i].RouteID })
+	for i, route := range s.Routes {
+		if len(s.Agencies) > 1 || route.AgencyID != "" {
+			v.reference(FILE_ROUTES, i, "agency_id", route.AgencyID, agencies)
+		}
+		if route.RouteType == ROUTE_TYPE_UNKNOWN {
+			v.report(FILE_ROUTES, i, "missing route_type")
+		}
+	}
+
+	// services are only checked when the feed has a calendar
+	services := make(map[string]bool)
+	for _, c := range s.Calendars {
+		services[c.ServiceID] = true
+	}
+	for _, d := range s.CalendarDates {
+		services[d.ServiceID] = true
+	}
+	shapes := make(map[string]bool)
+	for _, shape := range s.Shapes {
+		shapes[shape.ShapeID] = true
+	}
+	trips := v.ids(FILE_TRIPS, "trip_id", len(s.Trips), func(i int) string { return s.Trips[i].TripID })
+	for i, trip := range s.Trips {
+		v.reference(FILE_TRIPS, i, "route_id", trip.RouteID, routes)
+		if len(services) > 0 {
+			v.reference(FILE_TRIPS, i, "service_id", trip.ServiceID, services)
+		}
+		v.reference(FILE_TRIPS, i, "shape_id", trip.ShapeID, shapes)
+	}
+
+	v.stopTimes(s.StopTimes, stops, trips)
+
+	for i, f := range s.Frequencies {
+		v.reference(FILE_FREQUENCIES, i, "trip_id", f.TripID, trips)
+		if f.HeadwaySecs <= 0 {
+			v.report(FILE_FREQUENCIES, i, "headway_secs must be positive")
+		}
+		if !f.StartTime.IsSet() || !f.EndTime.IsSet() || f.EndTime <= f.StartTime {
+			v.report(FILE_FREQUENCIES, i, "end_time %s is not after start_time %s", f.EndTime, f.StartTime)
+		}
+	}
+	for i, t := range s.Transfers {
+		v.reference(FILE_TRANSFERS, i, "from_stop_id", t.FromStopID, stops)
+		v.reference(FILE_TRANSFERS, i, "to_stop_id", t.ToStopID, stops)
+		v.reference(FILE_TRANSFERS, i, "from_route_id", t.FromRouteID, routes)
+		v.reference(FILE_TRANSFERS, i, "to_route_id", t.ToRouteID, routes)
+		v.reference(FILE_TRANSFERS, i, "from_trip_id", t.FromTripID, trips)
+		v.reference(FILE_TRANSFERS, i, "to_trip_id", t.ToTripID, trips)
+		if t.FromRouteID != "" || t.ToRouteID != "" || t.FromTripID != "" || t.ToTripID != "" {
+			// the graph only has transfers between stops
+			v.report(FILE_TRANSFERS, i, "transfers of a route or trip are not supported, the row is ignored")
+		}
+	}
+	v.ids(FILE_PATHWAYS, "pathway_id", len(s.Pathways), func(i int) string { return s.Pathways[i].PathwayID })
+	for i, p := range s.Pathways {
+		v.reference(FILE_PATHWAYS, i, "from_stop_id", p.FromStopID, stops)
+		v.reference(FILE_PATHWAYS, i, "to_stop_id", p.ToStopID, stops)
+	}
+	fares := make(map[string]bool)
+	for _, fare := range s.FareAttributes {
+		fares[fare.FareID] = true
+	}
+	for i, rule := range s.FareRules {
+		v.reference(FILE_FARE_RULES, i, "fare_id", rule.FareID, fares)
+		v.reference(FILE_FARE_RULES, i, "route_id", rule.RouteID, routes)
+	}
+	if len(s.FeedInfo) > 1 {
+		v.report(FILE_FEED_INFO, 1, "more than one row")
+	}
+
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		return v.problems[i].File < v.problems[j].File
+	})
+	return v.problems
+}
+
+// stopTimes checks the references of stop_times.txt and that every trip
+// visits its stops in increasing stop_sequence without going back in time
+func (v *validator) stopTimes(stopTimes []*StopTime, stops, trips map[string]bool) {
+	byTrip := make(map[string][]int) // tripID -> rows
+	for i, st := range stopTimes {
+		if st.TripID == "" {
+			v.report(FILE_STOP_TIMES, i, "missing trip_id")
+			continue
+		}
+		v.reference(FILE_STOP_TIMES, i, "trip_id", st.TripID, trips)
+		if st.StopID == "" {
+			v.report(FILE_STOP_TIMES, i, "missing stop_id")
+		} else {
+			v.reference(FILE_STOP_TIMES, i, "stop_id", st.StopID, stops)
+		}
+		if st.ArrivalTime.IsSet() && st.DepartureTime.IsSet() && st.DepartureTime < st.ArrivalTime {
+			v.report(FILE_STOP_TIMES, i, "departure_time %s before arrival_time %s", st.DepartureTime, st.ArrivalTime)
+		}
+		byTrip[st.TripID] = append(byTrip[st.TripID], i)
+	}
+
+	tripIDs := make([]string, 0, len(byTrip))
+	for tripID := range byTrip {
+		tripIDs = append(tripIDs, tripID)
+	}
+	sort.Strings(tripIDs)
+	for _, tripID := range tripIDs {
+		rows := byTrip[tripID]
+		sort.SliceStable(rows, func(a, b int) bool {
+			return stopTimes[rows[a]].StopSequence < stopTimes[rows[b]].StopSequence
+		})
+		if len(rows) < 2 {
+			v.report(FILE_STOP_TIMES, rows[0], "trip %q has less than two stops", tripID)
+			continue
+		}
+		first, last := stopTimes[rows[0]], stopTimes[rows[len(rows)-1]]
+		if !first.DepartureTime.IsSet() && !first.ArrivalTime.IsSet() {
+			v.report(FILE_STOP_TIMES, rows[0], "first stop of trip %q has no time", tripID)
+		}
+		if !last.DepartureTime.IsSet() && !last.ArrivalTime.IsSet() {
+			v.report(FILE_STOP_TIMES, rows[len(rows)-1], "last stop of trip %q has no time", tripID)
+		}
+		previous := NO_TIME
+		for k, i := range rows {
+			st := stopTimes[i]
+			if k > 0 && st.StopSequence == stopTimes[rows[k-1]].StopSequence {
+				v.report(FILE_STOP_TIMES, i, "duplicate stop_sequence %d in trip %q", st.StopSequence, tripID)
+			}
+			arrival := st.ArrivalTime
+			if !arrival.IsSet() {
+				arrival = st.DepartureTime
+			}
+			if arrival.IsSet() && previous.IsSet() && arrival < previous {
+				v.report(FILE_STOP_TIMES, i, "trip %q arrives at %s, before leaving the previous stop at %s", tripID, arrival, previous)
+			}
+			if st.DepartureTime.IsSet() {
+				previous = st.DepartureTime
+			} else if arrival.IsSet() {
+				previous = arrival
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package gtfs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Watch calls onChange whenever the feed at source changes, checking every
+// interval until ctx is done. A change is only reported once the feed has
+// been left alone for an interval, so a feed being copied in isn't read half
+// written.
+func Watch(ctx context.Context, source string, interval time.Duration, onChange func()) {
+	loaded, err := ModTime(source)
+	if err != nil {
+		log.Printf("Couldn't watch %s, err: %v", source, err)
+	}
+	pending := loaded
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		modified, err := ModTime(source)
+		if err != nil {
+			// the feed may be in the middle of being replaced
+			continue
+		}
+		if modified.Equal(loaded) {
+			continue
+		}
+		if !modified.Equal(pending) {
+			pending = modified
+			continue
+		}
+		loaded = modified
+		onChange()
+	}
+}
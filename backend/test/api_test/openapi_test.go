@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/api"
+	"github.com/gorilla/mux"
+)
+
+// the document served must describe exactly the routes of the versioned API
+func TestOpenAPI_MatchesRoutes(t *testing.T) {
+	s := newServer(t)
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Parameters []struct {
+				Name     string `json:"name"`
+				Required bool   `json:"required"`
+			} `json:"parameters"`
+			Responses map[string]any `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	getJSON(t, s, "/api/v1/openapi.json", &doc)
+
+	var routed, documented []string
+	s.Router().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		if err != nil || !strings.HasPrefix(path, api.API_PREFIX+"/") {
+			return nil
+		}
+		for _, method := range methods {
+			routed = append(routed, strings.ToLower(method)+" "+strings.TrimPrefix(path, api.API_PREFIX))
+		}
+		return nil
+	})
+	for path, operations := range doc.Paths {
+		for method, operation := range operations {
+			documented = append(documented, method+" "+path)
+			if _, ok := operation.Responses["200"]; !ok {
+				t.Errorf("%s %s: no success response", method, path)
+			}
+			// every parameter is known to the handler, and the rest are rejected
+			url := api.API_PREFIX + path + "?"
+			for _, p := range operation.Parameters {
+				if p.Required {
+					url += p.Name + "=x&"
+				}
+			}
+			if err := getError(t, s, url+"undocumented=1", http.StatusBadRequest); !strings.Contains(err.Message, "undocumented") {
+				t.Errorf("%s %s: want the undocumented parameter rejected, got %+v", method, path, err)
+			}
+		}
+	}
+	sort.Strings(routed)
+	sort.Strings(documented)
+	if strings.Join(routed, ",") != strings.Join(documented, ",") {
+		t.Errorf("routes and document differ:\nrouted     %v\ndocumented %v", routed, documented)
+	}
+
+	journey := doc.Components.Schemas["Journey"]
+	leg := doc.Components.Schemas["Leg"]
+	for _, property := range []string{"legs", "departure", "transfers"} {
+		if journey.Properties[property] == nil {
+			t.Errorf("Journey schema: missing %s", property)
+		}
+	}
+	// the embedded line info is inlined like encoding/json does
+	for _, property := range []string{"from", "routeShortName", "tripHeadsign"} {
+		if leg.Properties[property] == nil {
+			t.Errorf("Leg schema: missing %s", property)
+		}
+	}
+	if leg.Properties["Edges"] != nil || leg.Properties["edges"] != nil {
+		t.Error("Leg schema: fields left out of the JSON should not be documented")
+	}
+}
@@ -0,0 +1,225 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Durelius/next-week/internal/api"
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// a small feed: metro A -> B -> C, and stop D 200 m from C
+var fixtureFeed = map[string]string{
+	"agency.txt": "agency_id,agency_name,agency_url,agency_timezone,agency_lang\n" +
+		"1,SL,http://sl.se,Europe/Stockholm,sv\n",
+	"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type\n" +
+		"A,Alpha,59.3000,18.0000,\n" +
+		"B,Beta,59.3100,18.0000,\n" +
+		"C,Gamma,59.3200,18.0000,\n" +
+		"D,Delta,59.3218,18.0000,\n",
+	"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type,route_url\n" +
+		"R1,1,13,,401,\n",
+	"trips.txt": "route_id,service_id,trip_id,trip_headsign,trip_short_name\n" +
+		"R1,S1,T1,Gamma,\n",
+	"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence,pickup_type,drop_off_type\n" +
+		"T1,08:00:00,08:00:00,A,1,0,0\n" +
+		"T1,08:05:00,08:06:00,B,2,0,0\n" +
+		"T1,08:10:00,08:10:00,C,3,0,0\n",
+}
+
+// helper: writes the feed to a new directory
+func writeFeedDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// helper: a server answering from the fixture feed
+func newServer(t *testing.T) *api.Server {
+	t.Helper()
+	return newServerWith(t, fixtureFeed)
+}
+
+// helper: a server answering from a feed
+func newServerWith(t *testing.T, files map[string]string) *api.Server {
+	t.Helper()
+	dir := writeFeedDir(t, files)
+	g, err := graph.Build(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := api.NewServer(g)
+	s.Source = dir
+	return s
+}
+
+// helper: sends a request to the server
+func serve(s *api.Server, method, url string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	return w
+}
+
+// helper: a GET request decoding the JSON answer into out
+func getJSON(t *testing.T, s *api.Server, url string, out any) {
+	t.Helper()
+	w := serve(s, http.MethodGet, url, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: want 200, got %d", url, w.Code)
+	}
+	if err := json.NewDecoder(w.Body).Decode(out); err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+}
+
+func TestGetStopsByName(t *testing.T) {
+	s := newServer(t)
+	var stops []graph.Stop
+	getJSON(t, s, "/stopbyname/alp", &stops)
+	if len(stops) != 1 || stops[0].StopID != "A" {
+		t.Errorf("want stop A, got %+v", stops)
+	}
+	getJSON(t, s, "/stopbyname/nowhere", &stops)
+	if len(stops) != 0 {
+		t.Errorf("want no stops, got %+v", stops)
+	}
+}
+
+func TestGetPath(t *testing.T) {
+	s := newServer(t)
+	var journey graph.Journey
+	getJSON(t, s, "/path/A/C/07:55", &journey)
+	if len(journey.Legs) != 1 {
+		t.Fatalf("want one ride, got %d legs", len(journey.Legs))
+	}
+	leg := journey.Legs[0]
+	if leg.TripID != "T1" || leg.TripInfo == nil || leg.RouteShortName != "13" || leg.To.StopID != "C" {
+		t.Errorf("want line 13 to C, got %+v", leg)
+	}
+
+	if w := serve(s, http.MethodGet, "/path/A/C/xx:yy", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid time: want 400, got %d", w.Code)
+	}
+	if w := serve(s, http.MethodGet, "/path/A/C/07:55?profile=flying", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown profile: want 400, got %d", w.Code)
+	}
+}
+
+func TestGetPathBetweenPlaces(t *testing.T) {
+	s := newServer(t)
+	var journey graph.Journey
+	getJSON(t, s, "/path?from=A&toLat=59.3218&toLon=18.0&time=07:55", &journey)
+	if len(journey.Legs) != 2 || journey.Legs[1].TransferType != graph.WALK_EDGE {
+		t.Fatalf("want a ride and a walk, got %+v", journey.Legs)
+	}
+
+	if w := serve(s, http.MethodGet, "/path?from=A&toLat=91&toLon=18", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid coordinate: want 400, got %d", w.Code)
+	}
+	if w := serve(s, http.MethodGet, "/path?from=Z&to=C", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown stop: want 404, got %d", w.Code)
+	}
+}
+
+func TestGetJourneys(t *testing.T) {
+	s := newServer(t)
+	var options []graph.RouteOption
+	getJSON(t, s, "/journeys/A/C/07:55", &options)
+	if len(options) != 1 || options[0].Transfers != 0 {
+		t.Errorf("want one direct option, got %+v", options)
+	}
+	getJSON(t, s, "/journeys/A/C/07:00?until=08:30", &options)
+	if len(options) != 1 {
+		t.Errorf("want the one departure in the window, got %d", len(options))
+	}
+	if w := serve(s, http.MethodGet, "/journeys/A/C/07:00?until=12:00", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("too long window: want 400, got %d", w.Code)
+	}
+}
+
+func TestGetStopsNear(t *testing.T) {
+	s := newServer(t)
+	var stops []graph.NearbyStop
+	getJSON(t, s, "/stopsnear/59.3218/18.0/300", &stops)
+	if len(stops) != 2 || stops[0].Stop.StopID != "D" {
+		t.Errorf("want D then C, got %+v", stops)
+	}
+	if w := serve(s, http.MethodGet, "/stopsnear/59.32/18.0/100000", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("too large radius: want 400, got %d", w.Code)
+	}
+}
+
+func TestReloadEndpoint(t *testing.T) {
+	s := newServer(t)
+	old := s.Graph()
+	if w := serve(s, http.MethodPost, "/admin/reload", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("without admin token: want 401, got %d", w.Code)
+	}
+	s.AdminToken = "secret"
+	wrong := http.Header{"Authorization": {"Bearer guess"}}
+	if w := serve(s, http.MethodPost, "/admin/reload", wrong); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: want 401, got %d", w.Code)
+	}
+	if s.Graph() != old {
+		t.Fatal("an unauthorized reload should keep the graph")
+	}
+
+	stops := fixtureFeed["stops.txt"] + "E,Epsilon,60.0000,18.0000,\n"
+	os.WriteFile(filepath.Join(s.Source, "stops.txt"), []byte(stops), 0o644)
+	w := serve(s, http.MethodPost, "/admin/reload", http.Header{"Authorization": {"Bearer secret"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", w.Code)
+	}
+	if s.Graph() == old || s.Graph().GetVertexByID("E") == nil {
+		t.Error("want the graph of the changed feed in use")
+	}
+	// a request holding the old graph can still search it
+	a, c := old.GetVertexByID("A"), old.GetVertexByID("C")
+	if old.GetVertexByID("E") != nil || len(old.FindRouteBetween(graph.StopPlace(a), graph.StopPlace(c), timeAt(t, "07:55"), graph.DefaultProfile())) == 0 {
+		t.Error("the old graph should be unchanged")
+	}
+}
+
+func TestReload_KeepsGraphOnError(t *testing.T) {
+	s := newServer(t)
+	old := s.Graph()
+	os.Remove(filepath.Join(s.Source, "stops.txt"))
+	if _, err := s.Reload(); err == nil {
+		t.Fatal("want an error for a feed without stops")
+	}
+	if s.Graph() != old {
+		t.Error("want the previous graph kept in use")
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	w := serve(newServer(t), http.MethodOptions, "/path/A/C/07:55", nil)
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("want 204 with CORS headers, got %d %v", w.Code, w.Header())
+	}
+}
+
+// helper: a time of day today in UTC
+func timeAt(t *testing.T, clock string) time.Time {
+	t.Helper()
+	c, err := time.Parse("15:04", clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), c.Hour(), c.Minute(), 0, 0, time.UTC)
+}
@@ -0,0 +1,204 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/api"
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// helper: a GET request that should fail, returning the error
+func getError(t *testing.T, s *api.Server, url string, status int) *api.Error {
+	t.Helper()
+	w := serve(s, http.MethodGet, url, nil)
+	if w.Code != status {
+		t.Fatalf("GET %s: want %d, got %d: %s", url, status, w.Code, w.Body)
+	}
+	var body api.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil || body.Error == nil {
+		t.Fatalf("GET %s: want an error object, got %s", url, w.Body)
+	}
+	return body.Error
+}
+
+func TestV1_Stops(t *testing.T) {
+	s := newServer(t)
+	var stops []graph.Stop
+	getJSON(t, s, "/api/v1/stops?name=gam", &stops)
+	if len(stops) != 1 || stops[0].StopID != "C" {
+		t.Errorf("want stop C, got %+v", stops)
+	}
+	getJSON(t, s, "/api/v1/stops?name=gamam", &stops)
+	if len(stops) != 1 || stops[0].StopID != "C" {
+		t.Errorf("with a typo: want stop C, got %+v", stops)
+	}
+	getJSON(t, s, "/api/v1/stops?name=a&limit=1", &stops)
+	if len(stops) != 1 || stops[0].StopID != "A" {
+		t.Errorf("want Alpha, got %+v", stops)
+	}
+	var near []graph.NearbyStop
+	getJSON(t, s, "/api/v1/stops/near?lat=59.3218&lon=18.0", &near)
+	if len(near) != 2 {
+		t.Errorf("want D and C within the default radius, got %+v", near)
+	}
+	// further than anyone walks between stops, but still a nearby stop
+	getJSON(t, s, "/api/v1/stops/near?lat=59.3218&lon=18.0&radius=1500", &near)
+	if len(near) != 3 || near[2].Stop.StopID != "B" {
+		t.Errorf("want D, C and B within 1500 m, got %+v", near)
+	}
+}
+
+func TestV1_Stations(t *testing.T) {
+	// Gamma is a station with the platform C and the platform E without rides
+	files := make(map[string]string)
+	for name, content := range fixtureFeed {
+		files[name] = content
+	}
+	files["stops.txt"] = "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+		"A,Alpha,59.3000,18.0000,,\n" +
+		"B,Beta,59.3100,18.0000,,\n" +
+		"G,Gamma,59.3200,18.0000,1,\n" +
+		"C,Gamma,59.3200,18.0000,,G\n" +
+		"E,Gamma,59.3202,18.0000,,G\n" +
+		"D,Delta,59.3218,18.0000,,\n"
+	s := newServerWith(t, files)
+
+	var stations []graph.Station
+	getJSON(t, s, "/api/v1/stations?name=gama", &stations)
+	if len(stations) != 1 || stations[0].ID != "G" || len(stations[0].Platforms) != 2 {
+		t.Fatalf("want the station G with two platforms, got %+v", stations)
+	}
+	if stations[0].Platforms[0].StopID != "C" {
+		t.Errorf("want the busy platform C first, got %s", stations[0].Platforms[0].StopID)
+	}
+	var journey graph.Journey
+	getJSON(t, s, "/api/v1/journey?from=A&to=G&time=07:55", &journey)
+	if len(journey.Legs) != 1 || journey.Legs[0].To.StopID != "C" {
+		t.Errorf("want the ride to the platform C, got %+v", journey.Legs)
+	}
+	getJSON(t, s, "/api/v1/journey?from=A&to=G&time=08:30&arriveBy=true", &journey)
+	if len(journey.Legs) != 1 || journey.Legs[0].To.StopID != "C" || journey.Arrival.Minute() != 10 {
+		t.Errorf("arriveBy to a station with two platforms: want the 08:10 arrival at C, got %+v", journey)
+	}
+	var options []graph.RouteOption
+	getJSON(t, s, "/api/v1/journeys?from=A&to=G&time=07:00&until=08:30", &options)
+	if len(options) != 1 || options[0].Arrival.Hour() != 8 || options[0].Arrival.Minute() != 10 {
+		t.Errorf("want one option arriving at the platform C at 08:10, got %+v", options)
+	}
+}
+
+func TestV1_Journey(t *testing.T) {
+	s := newServer(t)
+	var journey graph.Journey
+	getJSON(t, s, "/api/v1/journey?from=A&to=C&time=07:55", &journey)
+	if len(journey.Legs) != 1 || journey.Legs[0].RouteShortName != "13" {
+		t.Errorf("want line 13, got %+v", journey.Legs)
+	}
+	getJSON(t, s, "/api/v1/journey?from=A&to=C&time=8:30&arriveBy=true", &journey)
+	if journey.Arrival.Hour() != 8 || journey.Arrival.Minute() != 10 {
+		t.Errorf("want the 08:10 arrival, got %s", journey.Arrival)
+	}
+	getJSON(t, s, "/api/v1/journey?from=A&toLat=59.3218&toLon=18.0&time=07:55", &journey)
+	if len(journey.Legs) != 2 {
+		t.Errorf("want a ride and a walk, got %+v", journey.Legs)
+	}
+}
+
+func TestV1_Isochrone(t *testing.T) {
+	s := newServer(t)
+	var collection api.FeatureCollection
+	getJSON(t, s, "/api/v1/isochrone?from=A&time=07:55", &collection)
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 3 {
+		t.Fatalf("want a feature per default band, got %+v", collection)
+	}
+	stopsOf := func(f *api.Feature) []string {
+		ids := []string{}
+		for _, stop := range f.Properties.Stops {
+			ids = append(ids, stop.StopID)
+		}
+		return ids
+	}
+	// A at once, B after 10 and C after 15 minutes, D is a walk further
+	first, second, third := collection.Features[0], collection.Features[1], collection.Features[2]
+	if got := stopsOf(first); len(got) != 3 || got[0] != "A" || got[2] != "C" {
+		t.Errorf("within 15 minutes: want A, B and C, got %v", got)
+	}
+	if got := stopsOf(second); len(got) != 1 || got[0] != "D" || second.Properties.FromMinutes != 15 {
+		t.Errorf("within 30 minutes: want D, got %v", got)
+	}
+	if len(third.Properties.Stops) != 0 || third.Properties.Minutes != 45 {
+		t.Errorf("within 45 minutes: want nothing, got %+v", third.Properties)
+	}
+	if c := first.Geometry.Coordinates[2]; first.Geometry.Type != "MultiPoint" || c != [2]float64{18.0, 59.32} {
+		t.Errorf("want C as [lon, lat], got %s %v", first.Geometry.Type, c)
+	}
+	// the bands asked for, the search ends with the last one
+	getJSON(t, s, "/api/v1/isochrone?from=A&time=07:55&bands=10,14", &collection)
+	if len(collection.Features) != 2 || collection.Features[1].Properties.Minutes != 14 {
+		t.Fatalf("want the bands 10 and 14, got %+v", collection)
+	}
+	if got := stopsOf(collection.Features[1]); len(got) != 0 {
+		t.Errorf("want C after the last band, got %v", got)
+	}
+	if e := getError(t, s, "/api/v1/isochrone?from=X", http.StatusNotFound); e.Code != api.CODE_UNKNOWN_STOP {
+		t.Errorf("unknown stop: got %s", e.Code)
+	}
+}
+
+func TestV1_Journeys(t *testing.T) {
+	s := newServer(t)
+	var options []graph.RouteOption
+	getJSON(t, s, "/api/v1/journeys?from=A&to=C&time=07:00&until=08:30", &options)
+	if len(options) != 1 {
+		t.Errorf("want one option, got %d", len(options))
+	}
+}
+
+func TestV1_Errors(t *testing.T) {
+	s := newServer(t)
+	tests := []struct {
+		url    string
+		status int
+		code   string
+	}{
+		{"/api/v1/journey?from=A&to=C&time=7", http.StatusBadRequest, api.CODE_BAD_TIME},
+		{"/api/v1/journey?from=A&to=C&time=25:00", http.StatusBadRequest, api.CODE_BAD_TIME},
+		{"/api/v1/journey?from=A&to=C&time=07:55&date=tomorrow", http.StatusBadRequest, api.CODE_BAD_TIME},
+		{"/api/v1/journey?from=Z&to=C&time=07:55", http.StatusNotFound, api.CODE_UNKNOWN_STOP},
+		{"/api/v1/journey?from=C&to=A&time=07:55", http.StatusNotFound, api.CODE_NO_ROUTE},
+		{"/api/v1/journey?to=C", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/journey?from=A&to=C&arriveBy=maybe", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/journey?fromLat=59.3&fromLon=18&to=C&arriveBy=true", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/journey?from=A&to=C&exclude=rocket", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/journeys?from=A&to=C&time=07:00&until=12:00", http.StatusBadRequest, api.CODE_BAD_TIME},
+		{"/api/v1/journeys?from=A", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/isochrone?from=A&bands=30,15", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/isochrone?from=A&bands=0", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/isochrone?from=A&bands=121", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/isochrone?from=A&bands=1,2,3,4,5,6,7,8,9", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/stops", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/stops?name=a&sort=id", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/stops?name=a&limit=0", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/stops/near?lat=north&lon=18", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+		{"/api/v1/stops/near?lat=59&lon=18&radius=-1", http.StatusBadRequest, api.CODE_INVALID_PARAMETER},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := getError(t, s, tt.url, tt.status); err.Code != tt.code || err.Message == "" {
+				t.Errorf("want code %s, got %+v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestGetPath_ShortTime(t *testing.T) {
+	s := newServer(t)
+	// the time used to be sliced, short input panicked
+	for _, clock := range []string{"7", "7:", "0755", "ab:cd"} {
+		if err := getError(t, s, "/path/A/C/"+clock, http.StatusBadRequest); err.Code != api.CODE_BAD_TIME {
+			t.Errorf("%q: want code %s, got %+v", clock, api.CODE_BAD_TIME, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package graph_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+func arriveByGraph(t *testing.T) (*graph.SLGraph, *graph.Vertex, *graph.Vertex) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.31", "18.01")
+	c := addStop(t, g, "C", "Gamma", "59.32", "18.02")
+	addRide(t, g, a, b, "ab1", "", hms(7, 0), hms(7, 10))
+	addRide(t, g, a, b, "ab2", "", hms(7, 30), hms(7, 40))
+	addRide(t, g, a, b, "ab3", "", hms(8, 0), hms(8, 10))
+	addRide(t, g, b, c, "bc1", "", hms(7, 45), hms(7, 55))
+	addRide(t, g, b, c, "bc2", "", hms(8, 12), hms(8, 22))
+	addRide(t, g, b, c, "bc3", "", hms(8, 30), hms(8, 40))
+	return g, a, c
+}
+
+func TestFindRouteArriveBy_LatestDeparture(t *testing.T) {
+	g, a, c := arriveByGraph(t)
+
+	path := g.FindRouteArriveBy(a, c, at(t, "2026-10-16 08:25"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"ab3", "bc2"}) {
+		t.Fatalf("want [ab3 bc2], got %v", got)
+	}
+	if want := at(t, "2026-10-16 08:00"); !path[0].DepartureTime.Equal(want) {
+		t.Errorf("departure: want %s, got %s", want, path[0].DepartureTime)
+	}
+	last := path[len(path)-1]
+	if last.Destination().StopID != "C" {
+		t.Errorf("path should end at C, ends at %s", last.Destination().StopID)
+	}
+	if want := at(t, "2026-10-16 08:22"); !last.ArrivalTime.Equal(want) {
+		t.Errorf("arrival: want %s, got %s", want, last.ArrivalTime)
+	}
+}
+
+func TestFindRouteArriveBy_MinTransferTime(t *testing.T) {
+	g, a, c := arriveByGraph(t)
+	profile := graph.DefaultProfile()
+	profile.MinTransferTime = 5 * 60
+
+	// ab3 reaches B at 08:10, too close to bc2 at 08:12
+	path := g.FindRouteArriveBy(a, c, at(t, "2026-10-16 08:25"), profile)
+	if got := tripsOf(path); !slices.Equal(got, []string{"ab2", "bc2"}) {
+		t.Fatalf("want [ab2 bc2], got %v", got)
+	}
+}
+
+func TestFindRouteArriveBy_FallsBackToPreviousDay(t *testing.T) {
+	g, a, c := arriveByGraph(t)
+
+	// nothing arrives by 07:50, the latest option is the day before
+	path := g.FindRouteArriveBy(a, c, at(t, "2026-10-16 07:50"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"ab3", "bc3"}) {
+		t.Fatalf("want [ab3 bc3], got %v", got)
+	}
+	if want := at(t, "2026-10-15 08:00"); !path[0].DepartureTime.Equal(want) {
+		t.Errorf("departure: want %s, got %s", want, path[0].DepartureTime)
+	}
+}
+
+func TestFindRouteArriveBy_PreviousEvening(t *testing.T) {
+	g, a, _, c := overnightGraph(t)
+
+	// arriving by 06:00 means taking the night trip that left before midnight
+	path := g.FindRouteArriveBy(a, c, at(t, "2026-10-17 06:00"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"night", "morning"}) {
+		t.Fatalf("want [night morning], got %v", got)
+	}
+	if want := at(t, "2026-10-17 00:20"); !path[0].DepartureTime.Equal(want) {
+		t.Errorf("departure: want %s, got %s", want, path[0].DepartureTime)
+	}
+}
@@ -0,0 +1,90 @@
+package graph_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// helper: a YYYYMMDD feed date
+func feedDate(s string) gtfs.Date {
+	var d gtfs.Date
+	if err := d.UnmarshalCSV(s); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func weekdayCalendar() *graph.ServiceCalendar {
+	return graph.NewServiceCalendar(
+		[]*gtfs.Calendar{
+			{ServiceID: "weekday", Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true, StartDate: feedDate("20260101"), EndDate: feedDate("20261231")},
+			{ServiceID: "weekend", Saturday: true, Sunday: true, StartDate: feedDate("20260101"), EndDate: feedDate("20261231")},
+		},
+		[]*gtfs.CalendarDate{
+			// christmas eve on a thursday runs the weekend timetable
+			{ServiceID: "weekday", Date: feedDate("20261224"), ExceptionType: gtfs.EXCEPTION_REMOVED},
+			{ServiceID: "weekend", Date: feedDate("20261224"), ExceptionType: gtfs.EXCEPTION_ADDED},
+			{ServiceID: "extra", Date: feedDate("20260606"), ExceptionType: gtfs.EXCEPTION_ADDED},
+		},
+	)
+}
+
+func TestServiceCalendar_IsActive(t *testing.T) {
+	sc := weekdayCalendar()
+	tests := []struct {
+		service string
+		date    string
+		want    bool
+	}{
+		{"weekday", "2026-10-16", true},  // friday
+		{"weekday", "2026-10-18", false}, // sunday
+		{"weekend", "2026-10-18", true},
+		{"weekday", "2026-12-24", false},
+		{"weekend", "2026-12-24", true},
+		{"extra", "2026-06-06", true},
+		{"extra", "2026-06-07", false},
+		{"weekday", "2027-01-04", false}, // after end date
+		{"unknown", "2026-10-16", false},
+	}
+	for _, tt := range tests {
+		if got := sc.IsActive(tt.service, date(t, tt.date)); got != tt.want {
+			t.Errorf("IsActive(%s, %s): want %v, got %v", tt.service, tt.date, tt.want, got)
+		}
+	}
+}
+
+func TestServiceCalendar_EmptyRunsEverything(t *testing.T) {
+	sc := graph.NewServiceCalendar(nil, nil)
+	if !sc.IsActive("anything", date(t, "2026-10-18")) {
+		t.Error("empty calendar should consider every service active")
+	}
+	if sc.ServicesOn(date(t, "2026-10-18")) != nil {
+		t.Error("empty calendar should return nil service set")
+	}
+}
+
+func TestFindRoute_UsesOnlyTripsRunningThatDay(t *testing.T) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.31", "18.01")
+	// weekday trip leaves first, weekend trip 20 minutes later
+	addRide(t, g, a, b, "wd", "weekday", hms(8, 0), hms(8, 10))
+	addRide(t, g, a, b, "we", "weekend", hms(8, 20), hms(8, 30))
+	g.SetCalendar(weekdayCalendar())
+
+	path := g.FindRoute(a, b, at(t, "2026-10-16 07:50"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"wd"}) {
+		t.Errorf("friday: want [wd], got %v", got)
+	}
+	path = g.FindRoute(a, b, at(t, "2026-10-18 07:50"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"we"}) {
+		t.Errorf("sunday: want [we], got %v", got)
+	}
+	path = g.FindRoute(a, b, at(t, "2027-02-01 07:50"), graph.DefaultProfile())
+	if path != nil {
+		t.Errorf("outside calendar: want no route, got %v", tripsOf(path))
+	}
+}
@@ -0,0 +1,72 @@
+package graph_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// Run with -race: many searches share the graph while it's being changed
+func TestConcurrentSearches(t *testing.T) {
+	g, err := graph.NewFromFeed(loadFeed(t, fixtureFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, c := g.GetVertexByID("A"), g.GetVertexByID("C")
+	departure := at(t, "2026-10-16 07:55")
+	arrival := at(t, "2026-10-16 08:30") // t.Fatal may only be called from the test goroutine
+	profile := graph.DefaultProfile()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	// a writer adding and removing a stop with a ride to A, never on the route to C
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			x := graph.NewVertex("X")
+			x.SetMetadata(&graph.Stop{StopID: "X", StopName: "Xi", StopLatitude: "59.3000", StopLongitude: "18.0010"})
+			g.AddVertex(x)
+			if _, err := g.AddEdge(x, a, graph.EdgeProperties{TripID: "TX", Departure: hms(7, 0), Arrival: hms(7, 5), TransferType: graph.COMMUTE_EDGE}); err != nil {
+				t.Error(err)
+				return
+			}
+			g.RemoveVertices(x)
+		}
+	}()
+
+	var searches sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		searches.Add(1)
+		go func() {
+			defer searches.Done()
+			for j := 0; j < 20; j++ {
+				if path := g.FindRoute(a, c, departure, profile); len(path) == 0 || path[len(path)-1].Destination().StopID != "C" {
+					t.Error("FindRoute: want a route to C")
+				}
+				if options := g.FindParetoRoutes(a, c, departure, profile); len(options) == 0 {
+					t.Error("FindParetoRoutes: want an option")
+				}
+				if path := g.FindRouteArriveBy(a, c, arrival, profile); len(path) == 0 {
+					t.Error("FindRouteArriveBy: want a route")
+				}
+				to := graph.CoordinatePlace(59.3200, 18.0020)
+				if path := g.FindRouteBetween(graph.StopPlace(a), to, departure, profile); len(path) == 0 {
+					t.Error("FindRouteBetween: want a route")
+				}
+				g.StopsWithinRadius(59.3, 18.0, 500)
+				g.FindStopsByName("alpha")
+				g.EdgesOf(a)
+			}
+		}()
+	}
+	searches.Wait()
+	close(done)
+	wg.Wait()
+}
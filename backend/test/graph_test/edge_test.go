@@ -0,0 +1,149 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+func TestAddEdge_ParallelTrips(t *testing.T) {
+	g := graph.New()
+	a := g.AddVertexByLabel("A")
+	b := g.AddVertexByLabel("B")
+
+	for _, tripID := range []string{"t1", "t2", "t3"} {
+		if _, err := g.AddEdge(a, b, graph.EdgeProperties{TripID: tripID, TransferType: graph.COMMUTE_EDGE}); err != nil {
+			t.Fatalf("AddEdge(%s): %v", tripID, err)
+		}
+	}
+
+	if g.Size() != 3 {
+		t.Errorf("Size: want 3, got %d", g.Size())
+	}
+	if got := len(g.AllEdges()); got != 3 {
+		t.Errorf("AllEdges: want 3, got %d", got)
+	}
+	if got := len(g.EdgesBetween(a, b)); got != 3 {
+		t.Errorf("EdgesBetween: want 3, got %d", got)
+	}
+	if a.OutDegree() != 3 || b.InDegree() != 3 {
+		t.Errorf("degrees: want out 3 / in 3, got out %d / in %d", a.OutDegree(), b.InDegree())
+	}
+	if !g.ContainsTripEdge(a, b, "t2") {
+		t.Error("ContainsTripEdge(t2) should be true")
+	}
+	if g.ContainsTripEdge(b, a, "t2") {
+		t.Error("ContainsTripEdge(B->A) should be false")
+	}
+	if e := g.GetEdge(a, b, "t3"); e == nil || e.Metadata.TripID != "t3" {
+		t.Errorf("GetEdge(t3): got %v", e)
+	}
+}
+
+func TestAddEdge_DuplicateTrip(t *testing.T) {
+	g := graph.New()
+	a := g.AddVertexByLabel("A")
+	b := g.AddVertexByLabel("B")
+
+	if _, err := g.AddEdge(a, b, graph.EdgeProperties{TripID: "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.AddEdge(a, b, graph.EdgeProperties{TripID: "t1"}); !errors.Is(err, graph.ErrEdgeAlreadyExists) {
+		t.Errorf("want ErrEdgeAlreadyExists, got %v", err)
+	}
+	if g.Size() != 1 || a.OutDegree() != 1 {
+		t.Errorf("duplicate should not be stored, size %d, out degree %d", g.Size(), a.OutDegree())
+	}
+}
+
+func TestAddEdge_DuplicateAfterRemovingFirstRide(t *testing.T) {
+	g := graph.New()
+	a := g.AddVertexByLabel("A")
+	b := g.AddVertexByLabel("B")
+	first := graph.EdgeProperties{TripID: "t1", TransferType: graph.COMMUTE_EDGE, StopSequence: 1}
+	second := first
+	second.StopSequence = 3
+
+	for _, ride := range []graph.EdgeProperties{first, second} {
+		if _, err := g.AddEdge(a, b, ride); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the second ride is still there under its stop sequence
+	g.RemoveEdge(a, b, "t1")
+	if _, err := g.AddEdge(a, b, second); !errors.Is(err, graph.ErrEdgeAlreadyExists) {
+		t.Errorf("second ride again: want ErrEdgeAlreadyExists, got %v", err)
+	}
+	if _, err := g.AddEdge(a, b, first); err != nil {
+		t.Fatalf("re-adding the removed ride: %v", err)
+	}
+	if _, err := g.AddEdge(a, b, first); !errors.Is(err, graph.ErrEdgeAlreadyExists) {
+		t.Errorf("first ride again: want ErrEdgeAlreadyExists, got %v", err)
+	}
+	if got := len(g.EdgesBetween(a, b)); got != 2 {
+		t.Errorf("want the two rides, got %d", got)
+	}
+}
+
+func TestRemoveEdge_KeepsOtherTrips(t *testing.T) {
+	g := graph.New()
+	a := g.AddVertexByLabel("A")
+	b := g.AddVertexByLabel("B")
+	g.AddEdge(a, b, graph.EdgeProperties{TripID: "t1"})
+	g.AddEdge(a, b, graph.EdgeProperties{TripID: "t2"})
+
+	g.RemoveEdge(a, b, "t1")
+
+	if g.Size() != 1 {
+		t.Errorf("Size: want 1, got %d", g.Size())
+	}
+	if g.ContainsTripEdge(a, b, "t1") {
+		t.Error("t1 should be removed")
+	}
+	if !g.ContainsEdge(a, b) || !g.ContainsTripEdge(a, b, "t2") {
+		t.Error("t2 should still exist")
+	}
+	if a.OutDegree() != 1 || b.InDegree() != 1 {
+		t.Errorf("degrees: want out 1 / in 1, got out %d / in %d", a.OutDegree(), b.InDegree())
+	}
+	if edges := a.Edges(); len(edges) != 1 || edges[0].Metadata.TripID != "t2" {
+		t.Errorf("outgoing edges out of sync: %v", edges)
+	}
+
+	// removing an edge twice is a no-op
+	g.RemoveEdges(g.GetEdge(a, b, "t2"))
+	g.RemoveEdge(a, b, "t2")
+	if g.Size() != 0 || g.ContainsEdge(a, b) || a.OutDegree() != 0 {
+		t.Errorf("want empty edge set, size %d", g.Size())
+	}
+}
+
+func TestRemoveVertices_UpdatesEdgeIndex(t *testing.T) {
+	g := graph.New()
+	a := g.AddVertexByLabel("A")
+	b := g.AddVertexByLabel("B")
+	c := g.AddVertexByLabel("C")
+	g.AddEdge(a, b, graph.EdgeProperties{TripID: "t1"})
+	g.AddEdge(a, b, graph.EdgeProperties{TripID: "t2"})
+	g.AddEdge(b, c, graph.EdgeProperties{TripID: "t1"})
+	g.AddEdge(c, a, graph.EdgeProperties{})
+
+	g.RemoveVertices(b)
+
+	if g.Order() != 2 {
+		t.Errorf("Order: want 2, got %d", g.Order())
+	}
+	if g.Size() != 1 || len(g.AllEdges()) != 1 {
+		t.Errorf("Size: want 1, got %d (AllEdges %d)", g.Size(), len(g.AllEdges()))
+	}
+	if a.OutDegree() != 0 {
+		t.Errorf("A should have no outgoing edges, got %d", a.OutDegree())
+	}
+	if c.InDegree() != 0 {
+		t.Errorf("C should have no incoming edges, got %d", c.InDegree())
+	}
+	if got := len(g.EdgesOf(a)); got != 1 {
+		t.Errorf("EdgesOf(A): want 1, got %d", got)
+	}
+}
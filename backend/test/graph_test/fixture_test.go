@@ -0,0 +1,93 @@
+package graph_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// helper: add a stop with coordinates to the graph
+func addStop(t *testing.T, g *graph.SLGraph, id, name, lat, lon string) *graph.Vertex {
+	t.Helper()
+	v := graph.NewVertex(id)
+	v.SetMetadata(&graph.Stop{
+		StopID:        id,
+		StopName:      name,
+		StopNameLower: strings.ToLower(name),
+		StopLatitude:  lat,
+		StopLongitude: lon,
+	})
+	g.AddVertex(v)
+	return v
+}
+
+// helper: add a commute edge, times are seconds since the start of the service day
+func addRide(t *testing.T, g *graph.SLGraph, from, to *graph.Vertex, tripID, serviceID string, dep, arr int) {
+	t.Helper()
+	_, err := g.AddEdge(from, to, graph.EdgeProperties{
+		TripID:         tripID,
+		ServiceID:      serviceID,
+		Departure:      dep,
+		Arrival:        arr,
+		TransferType:   graph.COMMUTE_EDGE,
+		SourceStopName: from.Metadata().StopName,
+		DestStopName:   to.Metadata().StopName,
+	})
+	if err != nil {
+		t.Fatalf("AddEdge(%s -> %s, %s): %v", from.Label(), to.Label(), tripID, err)
+	}
+}
+
+// helper: collect the trip IDs used by a path
+func tripsOf(path []*graph.TimedEdge) []string {
+	var trips []string
+	for _, e := range path {
+		if len(trips) == 0 || trips[len(trips)-1] != e.Metadata.TripID {
+			trips = append(trips, e.Metadata.TripID)
+		}
+	}
+	return trips
+}
+
+// helper: seconds since the start of the service day
+func hms(h, m int) int {
+	return h*3600 + m*60
+}
+
+// helper: parse a YYYY-MM-DD date in UTC
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// helper: parse a "YYYY-MM-DD HH:MM" time in UTC
+func at(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// helper: add a walking edge in both directions
+func addWalk(t *testing.T, g *graph.SLGraph, a, b *graph.Vertex, seconds int) {
+	t.Helper()
+	for _, pair := range [][2]*graph.Vertex{{a, b}, {b, a}} {
+		_, err := g.AddEdge(pair[0], pair[1], graph.EdgeProperties{
+			Arrival:        seconds,
+			TransferType:   graph.WALK_EDGE,
+			SourceStopName: pair[0].Metadata().StopName,
+			DestStopName:   pair[1].Metadata().StopName,
+		})
+		if err != nil {
+			t.Fatalf("AddEdge(walk %s -> %s): %v", pair[0].Label(), pair[1].Label(), err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// the fixture feed with T1 repeated every 20 minutes between 06:00 and 07:00
+func frequencyFeed(t *testing.T, exactTimes string) *graph.SLGraph {
+	files := make(map[string]string)
+	for name, content := range fixtureFeed {
+		files[name] = content
+	}
+	files["frequencies.txt"] = "trip_id,start_time,end_time,headway_secs,exact_times\n" +
+		"T1,06:00:00,07:00:00,1200," + exactTimes + "\n"
+	g, err := graph.NewFromFeed(loadFeed(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestFrequencies_ExactTimes(t *testing.T) {
+	g := frequencyFeed(t, "1")
+	a, b := g.GetVertexByID("A"), g.GetVertexByID("B")
+
+	// 06:00, 06:20 and 06:40, the 08:00 template itself doesn't run
+	edges := g.EdgesBetween(a, b)
+	if len(edges) != 3 {
+		t.Fatalf("want 3 runs, got %d", len(edges))
+	}
+	if g.GetEdge(a, b, "T1") != nil {
+		t.Error("the template trip should not be added")
+	}
+	run := g.GetEdge(a, b, "T1@06:40:00")
+	if run == nil || run.Metadata.Departure != hms(6, 40) || run.Metadata.Arrival != hms(6, 45) {
+		t.Fatalf("want the 06:40 run A -> B arriving 06:45, got %+v", run)
+	}
+	if run.Metadata.Headway != 0 || run.Metadata.ServiceID != "S1" {
+		t.Errorf("exact run: want no headway and service S1, got %+v", run.Metadata)
+	}
+	// dwell times of the template are kept
+	if bc := g.GetEdge(b, g.GetVertexByID("C"), "T1@06:40:00"); bc == nil || bc.Metadata.Departure != hms(6, 46) {
+		t.Errorf("want B -> C departing 06:46, got %+v", bc)
+	}
+
+	path := g.FindRoute(a, g.GetVertexByID("C"), at(t, "2026-10-16 06:25"), graph.DefaultProfile())
+	if len(path) != 2 || path[0].Metadata.TripID != "T1@06:40:00" || path[1].Metadata.TripID != "T1@06:40:00" {
+		t.Errorf("want the 06:40 run all the way, got %v", tripsOf(path))
+	}
+}
+
+func TestFrequencies_HeadwayBased(t *testing.T) {
+	g := frequencyFeed(t, "0")
+	a, b := g.GetVertexByID("A"), g.GetVertexByID("B")
+
+	if got := len(g.EdgesBetween(a, b)); got != 3 {
+		t.Fatalf("want 3 runs, got %d", got)
+	}
+	if run := g.GetEdge(a, b, "T1@06:00:00"); run == nil || run.Metadata.Headway != 1200 {
+		t.Errorf("headway based run should carry its headway, got %+v", run)
+	}
+}
+
+func TestFrequencies_HeadwayBasedWaitsForBoarding(t *testing.T) {
+	departure := at(t, "2026-10-16 06:15")
+	for exactTimes, want := range map[string]string{
+		"1": "T1@06:20:00", // leaves exactly at 06:20
+		"0": "T1@06:40:00", // only promised within 20 minutes, half a headway is waited for
+	} {
+		g := frequencyFeed(t, exactTimes)
+		a, c := g.GetVertexByID("A"), g.GetVertexByID("C")
+		path := g.FindRoute(a, c, departure, graph.DefaultProfile())
+		if len(path) == 0 || path[0].Metadata.TripID != want {
+			t.Errorf("exact_times=%s: want the %s run, got %v", exactTimes, want, tripsOf(path))
+		}
+		options := g.FindParetoRoutes(a, c, departure, graph.DefaultProfile())
+		if len(options) == 0 || options[0].Edges[0].Metadata.TripID != want {
+			t.Errorf("exact_times=%s: want the Pareto search to take the %s run, got %+v", exactTimes, want, options)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package graph_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// A -> B -> C on one trip arriving at C after 40 minutes, then C -> D
+// arriving after 70 minutes
+func isochroneGraph(t *testing.T) (*graph.SLGraph, *graph.Vertex) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.31", "18.00")
+	c := addStop(t, g, "C", "Gamma", "59.32", "18.00")
+	d := addStop(t, g, "D", "Delta", "59.33", "18.00")
+	addRide(t, g, a, b, "t1", "", hms(8, 5), hms(8, 15))
+	addRide(t, g, b, c, "t1", "", hms(8, 20), hms(8, 40))
+	addRide(t, g, c, d, "t2", "", hms(8, 50), hms(9, 10))
+	// a slower way to B that the earliest arrival shouldn't use
+	addRide(t, g, a, b, "t3", "", hms(8, 5), hms(8, 30))
+	return g, a
+}
+
+func TestIsochrone(t *testing.T) {
+	g, a := isochroneGraph(t)
+	departure := at(t, "2026-10-16 08:00")
+
+	reached := g.Isochrone(graph.StopPlace(a), departure, 45*time.Minute, graph.DefaultProfile())
+	want := []struct {
+		stopID  string
+		minutes int
+	}{{"A", 0}, {"B", 15}, {"C", 40}}
+	if len(reached) != len(want) {
+		t.Fatalf("want %d stops within 45 minutes, got %+v", len(want), reached)
+	}
+	for i, w := range want {
+		if reached[i].Stop.StopID != w.stopID || reached[i].Seconds != w.minutes*60 {
+			t.Errorf("%d: want %s after %d minutes, got %s after %ds", i, w.stopID, w.minutes, reached[i].Stop.StopID, reached[i].Seconds)
+		}
+	}
+	if !reached[2].Arrival.Equal(departure.Add(40 * time.Minute)) {
+		t.Errorf("want C at 08:40, got %s", reached[2].Arrival)
+	}
+
+	// D is reached with a longer budget
+	if reached := g.Isochrone(graph.StopPlace(a), departure, 90*time.Minute, graph.DefaultProfile()); len(reached) != 4 {
+		t.Errorf("want all 4 stops within 90 minutes, got %d", len(reached))
+	}
+}
+
+func TestIsochrone_FromCoordinate(t *testing.T) {
+	g, _ := isochroneGraph(t)
+	// about 150 m from A, walked before the 08:05
+	reached := g.Isochrone(graph.CoordinatePlace(59.3013, 18.00), at(t, "2026-10-16 08:00"), 45*time.Minute, graph.DefaultProfile())
+	if len(reached) != 3 || reached[0].Stop.StopID != "A" || reached[0].Seconds == 0 {
+		t.Fatalf("want A after a walk then B and C, got %+v", reached)
+	}
+	if reached[1].Seconds != 15*60 {
+		t.Errorf("want B after 15 minutes, got %ds", reached[1].Seconds)
+	}
+}
+
+func TestIsochrone_StaysOnLaterVehicle(t *testing.T) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.31", "18.00")
+	c := addStop(t, g, "C", "Gamma", "59.32", "18.00")
+	// t1 reaches B first, too close to t2 going on to C to change to it
+	addRide(t, g, a, b, "t1", "", hms(8, 5), hms(8, 10))
+	addRide(t, g, a, b, "t2", "", hms(8, 6), hms(8, 12))
+	addRide(t, g, b, c, "t2", "", hms(8, 12), hms(8, 20))
+	addRide(t, g, b, c, "t3", "", hms(8, 40), hms(8, 50))
+	profile := graph.DefaultProfile()
+	profile.MinTransferTime = 5 * 60
+
+	reached := g.Isochrone(graph.StopPlace(a), at(t, "2026-10-16 08:00"), 60*time.Minute, profile)
+	if len(reached) != 3 || reached[1].Stop.StopID != "B" || reached[1].Seconds != 10*60 {
+		t.Fatalf("want B after 10 minutes on t1, got %+v", reached)
+	}
+	if reached[2].Stop.StopID != "C" || reached[2].Seconds != 20*60 {
+		t.Errorf("want C after 20 minutes staying on t2, got %s after %ds", reached[2].Stop.StopID, reached[2].Seconds)
+	}
+}
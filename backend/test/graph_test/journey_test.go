@@ -0,0 +1,80 @@
+package graph_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// helper: a used edge of a path, times are minutes after 08:00
+func timedEdge(t *testing.T, from, to *graph.Vertex, props graph.EdgeProperties, dep, arr int) *graph.TimedEdge {
+	base := at(t, "2026-10-16 08:00")
+	return &graph.TimedEdge{
+		Edge:          graph.NewEdge(from, to, props),
+		DepartureTime: base.Add(time.Duration(dep) * time.Minute),
+		ArrivalTime:   base.Add(time.Duration(arr) * time.Minute),
+	}
+}
+
+func TestNewJourney(t *testing.T) {
+	g := graph.New()
+	var v []*graph.Vertex
+	for _, id := range []string{"A", "B", "C", "D", "E", "F"} {
+		v = append(v, addStop(t, g, id, id, "59.3", "18.0"))
+	}
+	line := &graph.TripInfo{RouteShortName: "14", TripHeadsign: "Fruängen"}
+	ride := func(tripID string) graph.EdgeProperties {
+		return graph.EdgeProperties{TripID: tripID, TransferType: graph.COMMUTE_EDGE, Mode: graph.MODE_METRO, TripInfo: line}
+	}
+	walk := func(meters float64) graph.EdgeProperties {
+		return graph.EdgeProperties{TransferType: graph.WALK_EDGE, Distance: meters}
+	}
+	path := []*graph.TimedEdge{
+		timedEdge(t, v[0], v[1], ride("t1"), 0, 3),
+		timedEdge(t, v[1], v[2], ride("t1"), 4, 7),
+		timedEdge(t, v[2], v[3], walk(100), 7, 9),
+		timedEdge(t, v[3], v[4], walk(150), 9, 11),
+		timedEdge(t, v[4], v[5], ride("t2"), 15, 20),
+	}
+
+	journey := graph.NewJourney(path)
+	if len(journey.Legs) != 3 {
+		t.Fatalf("want ride, walk, ride, got %d legs", len(journey.Legs))
+	}
+	first := journey.Legs[0]
+	if first.TripID != "t1" || first.From.StopID != "A" || first.To.StopID != "C" || first.TripHeadsign != "Fruängen" {
+		t.Errorf("first leg: got %s from %s to %s", first.TripID, first.From.StopID, first.To.StopID)
+	}
+	if len(first.IntermediateStops) != 1 || first.IntermediateStops[0].Stop.StopID != "B" {
+		t.Fatalf("first leg should pass B, got %v", first.IntermediateStops)
+	}
+	if stop := first.IntermediateStops[0]; stop.Departure.Sub(stop.Arrival) != time.Minute {
+		t.Errorf("dwell at B: want 1 minute, got %s", stop.Departure.Sub(stop.Arrival))
+	}
+	if first.DurationSeconds != 7*60 || len(first.Edges) != 2 {
+		t.Errorf("first leg: want 7 minutes over 2 edges, got %d s over %d", first.DurationSeconds, len(first.Edges))
+	}
+	walking := journey.Legs[1]
+	if walking.TransferType != graph.WALK_EDGE || walking.From.StopID != "C" || walking.To.StopID != "E" || walking.Distance != 250 {
+		t.Errorf("walk leg: got %+v", walking)
+	}
+	if journey.Transfers != 1 || journey.WalkMeters != 250 || journey.WalkSeconds != 4*60 {
+		t.Errorf("totals: want 1 transfer and 250 m in 4 min, got %d, %.0f m, %d s", journey.Transfers, journey.WalkMeters, journey.WalkSeconds)
+	}
+	if journey.DurationSeconds != 20*60 || !journey.Departure.Equal(path[0].DepartureTime) {
+		t.Errorf("want 20 minutes from the first departure, got %d s", journey.DurationSeconds)
+	}
+}
+
+func TestNewJourney_SplitsTrips(t *testing.T) {
+	g, a, c := changeGraph(t)
+	path := g.FindRoute(a, c, at(t, "2026-10-16 07:55"), graph.DefaultProfile())
+	journey := graph.NewJourney(path)
+	if journey == nil || len(journey.Legs) != 2 || journey.Transfers != 1 {
+		t.Fatalf("want one leg per trip %v, got %v", tripsOf(path), journey)
+	}
+	if graph.NewJourney(nil) != nil {
+		t.Error("want nil for an empty route")
+	}
+}
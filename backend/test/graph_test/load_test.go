@@ -0,0 +1,205 @@
+package graph_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// a small feed: metro A -> B -> C, and stop D 200 m from C
+var fixtureFeed = map[string]string{
+	"agency.txt": "agency_id,agency_name,agency_url,agency_timezone,agency_lang\n" +
+		"1,SL,http://sl.se,Europe/Stockholm,sv\n",
+	"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type\n" +
+		"A,Alpha,59.3000,18.0000,\n" +
+		"B,Beta,59.3100,18.0000,\n" +
+		"C,Gamma,59.3200,18.0000,\n" +
+		"D,Delta,59.3218,18.0000,\n",
+	"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type,route_url\n" +
+		"R1,1,13,,401,\n",
+	"trips.txt": "route_id,service_id,trip_id,trip_headsign,trip_short_name\n" +
+		"R1,S1,T1,Gamma,\n",
+	"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence,pickup_type,drop_off_type\n" +
+		"T1,08:00:00,08:00:00,A,1,0,0\n" +
+		"T1,08:05:00,08:06:00,B,2,0,0\n" +
+		"T1,08:10:00,08:10:00,C,3,0,0\n",
+}
+
+// helper: write a feed to a temporary directory and load it
+func loadFeed(t *testing.T, files map[string]string) *gtfs.Feed {
+	t.Helper()
+	feed, err := gtfs.Load(writeFeedDir(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { feed.Close() })
+	return feed
+}
+
+func TestNewFromFeed(t *testing.T) {
+	g, err := graph.NewFromFeed(loadFeed(t, fixtureFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Order() != 4 {
+		t.Errorf("Order: want 4, got %d", g.Order())
+	}
+	a, b, c, d := g.GetVertexByID("A"), g.GetVertexByID("B"), g.GetVertexByID("C"), g.GetVertexByID("D")
+	ab := g.GetEdge(a, b, "T1")
+	if ab == nil || ab.Metadata.Departure != hms(8, 0) || ab.Metadata.Arrival != hms(8, 5) {
+		t.Fatalf("A -> B: got %+v", ab)
+	}
+	if ab.Metadata.ServiceID != "S1" || ab.Metadata.Mode != graph.MODE_METRO {
+		t.Errorf("A -> B: want service S1 by metro, got %s by %s", ab.Metadata.ServiceID, ab.Metadata.Mode)
+	}
+	if bc := g.GetEdge(b, c, "T1"); bc == nil || bc.Metadata.Departure != hms(8, 6) {
+		t.Errorf("B -> C should depart 08:06, got %+v", bc)
+	}
+	// C and D are 200 m apart, the others too far to walk
+	if !g.ContainsTripEdge(c, d, "") || !g.ContainsTripEdge(d, c, "") {
+		t.Error("want walking edges between C and D")
+	}
+	if g.ContainsTripEdge(a, b, "") {
+		t.Error("A and B are too far apart to walk")
+	}
+	if loc := g.Location().String(); loc != "Europe/Stockholm" {
+		t.Errorf("want the agency timezone, got %s", loc)
+	}
+	// no calendar, every service runs every day
+	path := g.FindRoute(a, d, at(t, "2026-10-16 07:55"), graph.DefaultProfile())
+	if len(path) != 3 {
+		t.Errorf("want metro to C then walk, got %d edges", len(path))
+	}
+}
+
+func TestNewFromFeed_MissingRequiredFile(t *testing.T) {
+	files := make(map[string]string)
+	for name, content := range fixtureFeed {
+		if name != "stop_times.txt" {
+			files[name] = content
+		}
+	}
+	if _, err := graph.NewFromFeed(loadFeed(t, files)); err == nil {
+		t.Error("want an error without stop_times.txt")
+	}
+}
+
+func TestNewFromFeed_TripInfo(t *testing.T) {
+	g, err := graph.NewFromFeed(loadFeed(t, fixtureFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := g.GetVertexByID("A"), g.GetVertexByID("B")
+	info := g.GetEdge(a, b, "T1").Metadata.TripInfo
+	if info == nil {
+		t.Fatal("commute edge without trip info")
+	}
+	want := graph.TripInfo{RouteID: "R1", RouteShortName: "13", TripHeadsign: "Gamma", AgencyID: "1", AgencyName: "SL"}
+	if *info != want {
+		t.Errorf("want %+v, got %+v", want, *info)
+	}
+	if g.GetEdge(b, g.GetVertexByID("C"), "T1").Metadata.TripInfo != info {
+		t.Error("edges of a trip should share its info")
+	}
+
+	data, err := json.Marshal(g.GetEdge(a, b, "T1").Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"routeShortName":"13"`, `"tripHeadsign":"Gamma"`, `"mode":"metro"`, `"agencyName":"SL"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("JSON %s is missing %s", data, field)
+		}
+	}
+	walk, err := json.Marshal(g.GetEdge(g.GetVertexByID("C"), g.GetVertexByID("D"), "").Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(walk), "routeId") {
+		t.Errorf("walking edges have no line, got %s", walk)
+	}
+}
+
+func TestNewFromFeed_TripPassingStopsTwice(t *testing.T) {
+	files := make(map[string]string)
+	for name, content := range fixtureFeed {
+		files[name] = content
+	}
+	// a circular line A -> B -> A -> B
+	files["stop_times.txt"] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence,pickup_type,drop_off_type\n" +
+		"T1,08:00:00,08:00:00,A,1,0,0\n" +
+		"T1,08:05:00,08:05:00,B,2,0,0\n" +
+		"T1,08:10:00,08:10:00,A,3,0,0\n" +
+		"T1,08:15:00,08:15:00,B,4,0,0\n"
+	g, err := graph.NewFromFeed(loadFeed(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := g.GetVertexByID("A"), g.GetVertexByID("B")
+	if edges := g.EdgesBetween(a, b); len(edges) != 2 {
+		t.Fatalf("want both rides A -> B, got %d", len(edges))
+	}
+	if first := g.GetEdge(a, b, "T1"); first == nil || first.Metadata.StopSequence != 1 {
+		t.Errorf("want the first ride, got %+v", first)
+	}
+	// the first ride is gone, the second is still caught
+	path := g.FindRoute(a, b, at(t, "2026-10-16 08:01"), graph.DefaultProfile())
+	if len(path) != 1 || path[0].Metadata.Departure != hms(8, 10) {
+		t.Errorf("want the 08:10 ride, got %v", path)
+	}
+	g.RemoveEdges(g.EdgesBetween(a, b)...)
+	if g.ContainsEdge(a, b) {
+		t.Error("want both rides removed")
+	}
+}
+
+func TestNewFromFeed_FeedTransfers(t *testing.T) {
+	files := make(map[string]string)
+	for name, content := range fixtureFeed {
+		files[name] = content
+	}
+	files["transfers.txt"] = "from_stop_id,to_stop_id,from_route_id,to_route_id,transfer_type,min_transfer_time\n" +
+		"A,B,,,2,120\n" +
+		"B,B,R1,R1,3,\n"
+	g, err := graph.NewFromFeed(loadFeed(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := g.GetVertexByID("A"), g.GetVertexByID("B")
+	// A and B are 1.1 km apart, too far for the default profile
+	walk := g.GetEdge(a, b, "")
+	if walk == nil || walk.Metadata.Distance < 1000 || walk.Metadata.Distance > 1200 {
+		t.Fatalf("want a walk of 1.1 km, got %+v", walk)
+	}
+	profile := graph.DefaultProfile()
+	for _, e := range g.FindRoute(a, b, at(t, "2026-10-16 08:30"), profile) {
+		if e.Edge == walk {
+			t.Errorf("want no walk further than %.0f m", profile.MaxWalkDistance)
+		}
+	}
+	profile.MaxWalkDistance = 2000
+	if path := g.FindRoute(a, b, at(t, "2026-10-16 08:30"), profile); len(path) != 1 || path[0].Edge != walk {
+		t.Errorf("want the walk, got %v", path)
+	}
+	// the rule for a route isn't applied to every vehicle at B
+	if rule := g.Transfers().Rule("B", "B"); rule != nil {
+		t.Errorf("want no rule at B, got %+v", rule)
+	}
+}
+
+// helper: writes the feed files to a new directory
+func writeFeedDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
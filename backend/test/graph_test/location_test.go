@@ -0,0 +1,83 @@
+package graph_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// A -> B by train leaving 08:10, the coordinates are about 150 m north of A and B
+func coordinateGraph(t *testing.T) (*graph.SLGraph, *graph.Vertex, *graph.Vertex) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.3000", "18.0000")
+	b := addStop(t, g, "B", "Beta", "59.4000", "18.0000")
+	addRide(t, g, a, b, "train", "", hms(8, 10), hms(8, 30))
+	return g, a, b
+}
+
+func TestFindRouteBetween_Coordinates(t *testing.T) {
+	g, _, _ := coordinateGraph(t)
+	from := graph.CoordinatePlace(59.3013, 18.0000)
+	to := graph.CoordinatePlace(59.4013, 18.0000)
+
+	path := g.FindRouteBetween(from, to, at(t, "2026-10-16 08:00"), graph.DefaultProfile())
+	if len(path) != 3 {
+		t.Fatalf("want walk, train, walk, got %d edges", len(path))
+	}
+	if path[0].Metadata.TransferType != graph.WALK_EDGE || path[0].Source().StopID != graph.ORIGIN_LABEL {
+		t.Errorf("first leg should walk from the origin, got %+v", path[0].Metadata)
+	}
+	if got := tripsOf(path[1:2]); !slices.Equal(got, []string{"train"}) {
+		t.Errorf("want the train, got %v", got)
+	}
+	if last := path[2]; last.Metadata.TransferType != graph.WALK_EDGE || last.Destination().StopID != graph.DESTINATION_LABEL {
+		t.Errorf("last leg should walk to the destination, got %+v", last.Metadata)
+	}
+	// the graph itself is unchanged
+	if g.GetVertexByID(graph.ORIGIN_LABEL) != nil || g.Order() != 2 || g.Size() != 1 {
+		t.Errorf("temporary vertices leaked into the graph, order %d, size %d", g.Order(), g.Size())
+	}
+}
+
+func TestFindRouteBetween_StopToCoordinate(t *testing.T) {
+	g, a, _ := coordinateGraph(t)
+
+	path := g.FindRouteBetween(graph.StopPlace(a), graph.CoordinatePlace(59.4013, 18.0000), at(t, "2026-10-16 08:00"), graph.DefaultProfile())
+	if len(path) != 2 || path[0].Metadata.TripID != "train" {
+		t.Fatalf("want train then walk, got %d edges", len(path))
+	}
+}
+
+func TestFindRouteBetween_NoStopInReach(t *testing.T) {
+	g, _, b := coordinateGraph(t)
+
+	// 5 km from the nearest stop
+	if path := g.FindRouteBetween(graph.CoordinatePlace(59.3450, 18.0000), graph.StopPlace(b), at(t, "2026-10-16 08:00"), graph.DefaultProfile()); path != nil {
+		t.Errorf("want no route, got %d edges", len(path))
+	}
+}
+
+func TestFindRouteBetween_WalkDirectly(t *testing.T) {
+	g, _, _ := coordinateGraph(t)
+
+	path := g.FindRouteBetween(graph.CoordinatePlace(59.3500, 18.0000), graph.CoordinatePlace(59.3510, 18.0000), at(t, "2026-10-16 08:00"), graph.DefaultProfile())
+	if len(path) != 1 || path[0].Metadata.TransferType != graph.WALK_EDGE {
+		t.Fatalf("want a single walk, got %d edges", len(path))
+	}
+}
+
+func TestFindRouteBetween_WalkTimedWithProfile(t *testing.T) {
+	g, _, _ := coordinateGraph(t)
+	slow := graph.DefaultProfile()
+	slow.WalkSpeed = 40
+
+	path := g.FindRouteBetween(graph.CoordinatePlace(59.3500, 18.0000), graph.CoordinatePlace(59.3510, 18.0000), at(t, "2026-10-16 08:00"), slow)
+	if len(path) != 1 {
+		t.Fatalf("want a single walk, got %d edges", len(path))
+	}
+	walk := path[0]
+	if seconds := int(walk.ArrivalTime.Sub(walk.DepartureTime).Seconds()); walk.Metadata.Arrival != seconds {
+		t.Errorf("want the edge to take the %ds of the journey, got %ds", seconds, walk.Metadata.Arrival)
+	}
+}
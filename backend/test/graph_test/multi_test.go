@@ -0,0 +1,81 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// A -> C leaves 08:10 arriving 08:40, B -> C leaves 08:20 arriving 08:30 and
+// A -> D leaves 08:10 arriving 08:25
+func multiGraph(t *testing.T) (g *graph.SLGraph, a, b, c, d *graph.Vertex) {
+	g = graph.New()
+	a = addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b = addStop(t, g, "B", "Beta", "59.30", "18.01")
+	c = addStop(t, g, "C", "Gamma", "59.40", "18.00")
+	d = addStop(t, g, "D", "Delta", "59.40", "18.01")
+	addRide(t, g, a, c, "slow", "", hms(8, 10), hms(8, 40))
+	addRide(t, g, b, c, "fast", "", hms(8, 20), hms(8, 30))
+	addRide(t, g, a, d, "other", "", hms(8, 10), hms(8, 25))
+	return g, a, b, c, d
+}
+
+func TestFindRouteMulti_SourceCosts(t *testing.T) {
+	g, a, b, c, _ := multiGraph(t)
+	departure := at(t, "2026-10-16 08:00")
+
+	// 10 minutes to B still catches the 08:20
+	route := g.FindRouteMulti([]graph.Endpoint{{Vertex: a}, {Vertex: b, Seconds: 600}}, []graph.Endpoint{{Vertex: c}}, departure, graph.DefaultProfile())
+	if route == nil || route.Source.Vertex != b || tripsOf(route.Path)[0] != "fast" {
+		t.Fatalf("want the fast trip from B, got %+v", route)
+	}
+	if route.Cost != 30*60 {
+		t.Errorf("want a cost of 30 minutes, got %d", route.Cost)
+	}
+
+	// 25 minutes to B misses it
+	route = g.FindRouteMulti([]graph.Endpoint{{Vertex: a}, {Vertex: b, Seconds: 1500}}, []graph.Endpoint{{Vertex: c}}, departure, graph.DefaultProfile())
+	if route == nil || route.Source.Vertex != a || tripsOf(route.Path)[0] != "slow" {
+		t.Fatalf("want the slow trip from A, got %+v", route)
+	}
+
+	// the penalty makes B worse without delaying it
+	route = g.FindRouteMulti([]graph.Endpoint{{Vertex: a}, {Vertex: b, Seconds: 600, Penalty: 1200}}, []graph.Endpoint{{Vertex: c}}, departure, graph.DefaultProfile())
+	if route == nil || route.Source.Vertex != a {
+		t.Errorf("want A with the penalty on B, got %+v", route)
+	}
+}
+
+func TestFindRouteMulti_TargetCosts(t *testing.T) {
+	g, a, _, c, d := multiGraph(t)
+	departure := at(t, "2026-10-16 08:00")
+	sources := []graph.Endpoint{{Vertex: a}}
+
+	// D is reached first, but 20 minutes from the destination
+	route := g.FindRouteMulti(sources, []graph.Endpoint{{Vertex: c}, {Vertex: d, Seconds: 1200}}, departure, graph.DefaultProfile())
+	if route == nil || route.Target.Vertex != c {
+		t.Fatalf("want C, got %+v", route)
+	}
+	route = g.FindRouteMulti(sources, []graph.Endpoint{{Vertex: c}, {Vertex: d, Seconds: 600}}, departure, graph.DefaultProfile())
+	if route == nil || route.Target.Vertex != d || route.Cost != 35*60 {
+		t.Fatalf("want D at a cost of 35 minutes, got %+v", route)
+	}
+}
+
+func TestFindRouteMulti_Edges(t *testing.T) {
+	g, a, b, c, _ := multiGraph(t)
+	departure := at(t, "2026-10-16 08:00")
+
+	// a source that is a target needs no edges
+	route := g.FindRouteMulti([]graph.Endpoint{{Vertex: a}}, []graph.Endpoint{{Vertex: c}, {Vertex: a, Seconds: 60}}, departure, graph.DefaultProfile())
+	if route == nil || route.Target.Vertex != a || len(route.Path) != 0 || route.Cost != 60 {
+		t.Errorf("want no edges to A, got %+v", route)
+	}
+	// nothing leaves C
+	if route := g.FindRouteMulti([]graph.Endpoint{{Vertex: c}}, []graph.Endpoint{{Vertex: b}}, departure, graph.DefaultProfile()); route != nil {
+		t.Errorf("want no route, got %+v", route)
+	}
+	if route := g.FindRouteMulti(nil, []graph.Endpoint{{Vertex: b}}, departure, graph.DefaultProfile()); route != nil {
+		t.Errorf("want no route without sources, got %+v", route)
+	}
+}
@@ -0,0 +1,67 @@
+package graph_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+func TestFindParetoRoutes(t *testing.T) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.31", "18.01")
+	c := addStop(t, g, "C", "Gamma", "59.301", "18.001")
+	d := addStop(t, g, "D", "Delta", "59.32", "18.02")
+	// slow but direct
+	addRide(t, g, a, d, "slow", "", hms(8, 0), hms(9, 0))
+	// dominated by the slow bus
+	addRide(t, g, a, d, "late", "", hms(9, 10), hms(9, 20))
+	// fast with a change at B
+	addRide(t, g, a, b, "f1", "", hms(8, 5), hms(8, 15))
+	addRide(t, g, b, d, "f2", "", hms(8, 20), hms(8, 30))
+	// fastest, but walk to C first
+	addWalk(t, g, a, c, 600)
+	addRide(t, g, c, d, "w", "", hms(8, 15), hms(8, 25))
+
+	options := g.FindParetoRoutes(a, d, at(t, "2026-10-16 08:00"), graph.DefaultProfile())
+
+	want := []struct {
+		trips     []string
+		transfers int
+		walk      int
+		arrival   string
+	}{
+		{[]string{"", "w"}, 0, 600, "2026-10-16 08:25"},
+		{[]string{"f1", "f2"}, 1, 0, "2026-10-16 08:30"},
+		{[]string{"slow"}, 0, 0, "2026-10-16 09:00"},
+	}
+	if len(options) != len(want) {
+		for _, o := range options {
+			t.Logf("option %v arrival %s", tripsOf(o.Edges), o.Arrival)
+		}
+		t.Fatalf("want %d options, got %d", len(want), len(options))
+	}
+	for i, w := range want {
+		o := options[i]
+		if got := tripsOf(o.Edges); !slices.Equal(got, w.trips) {
+			t.Errorf("option %d: want trips %v, got %v", i, w.trips, got)
+		}
+		if o.Transfers != w.transfers || o.WalkSeconds != w.walk {
+			t.Errorf("option %d: want %d transfers / %ds walk, got %d / %d", i, w.transfers, w.walk, o.Transfers, o.WalkSeconds)
+		}
+		if !o.Arrival.Equal(at(t, w.arrival)) {
+			t.Errorf("option %d: want arrival %s, got %s", i, w.arrival, o.Arrival)
+		}
+	}
+}
+
+func TestFindParetoRoutes_NoRoute(t *testing.T) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.31", "18.01")
+
+	if options := g.FindParetoRoutes(a, b, at(t, "2026-10-16 08:00"), graph.DefaultProfile()); len(options) != 0 {
+		t.Errorf("want no options, got %d", len(options))
+	}
+}
@@ -0,0 +1,97 @@
+package graph_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+func TestModeFromRouteType(t *testing.T) {
+	// route types as written in the feed
+	tests := map[string]graph.Mode{
+		"800":  graph.MODE_BUS,
+		"700":  graph.MODE_BUS,
+		"3":    graph.MODE_BUS,
+		"401":  graph.MODE_METRO,
+		"1":    graph.MODE_METRO,
+		"100":  graph.MODE_RAIL,
+		"900":  graph.MODE_TRAM,
+		"1000": graph.MODE_FERRY,
+		"null": graph.MODE_UNKNOWN,
+	}
+	for value, want := range tests {
+		var routeType gtfs.RouteType
+		if err := routeType.UnmarshalCSV(value); err != nil {
+			t.Fatal(err)
+		}
+		if got := graph.ModeFromRouteType(routeType); got != want {
+			t.Errorf("ModeFromRouteType(%s): want %s, got %s", value, want, got)
+		}
+	}
+}
+
+// A -> B either by a walk of the given distance, or by bus leaving 08:20
+func walkOrRideGraph(t *testing.T, meters float64) (*graph.SLGraph, *graph.Vertex, *graph.Vertex) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.303", "18.00")
+	for _, pair := range [][2]*graph.Vertex{{a, b}, {b, a}} {
+		g.AddEdge(pair[0], pair[1], graph.EdgeProperties{TransferType: graph.WALK_EDGE, Distance: meters, Arrival: 60})
+	}
+	g.AddEdge(a, b, graph.EdgeProperties{TripID: "bus", Mode: graph.MODE_BUS, TransferType: graph.COMMUTE_EDGE, Departure: hms(8, 20), Arrival: hms(8, 22)})
+	return g, a, b
+}
+
+func TestFindRoute_WalkSpeedFromProfile(t *testing.T) {
+	g, a, b := walkOrRideGraph(t, 320)
+
+	// 320 m at 80 m/min is 4 minutes
+	path := g.FindRoute(a, b, at(t, "2026-10-16 08:00"), graph.DefaultProfile())
+	if len(path) != 1 || path[0].Metadata.TransferType != graph.WALK_EDGE {
+		t.Fatalf("want a single walk, got %v", tripsOf(path))
+	}
+	if want := at(t, "2026-10-16 08:04"); !path[0].ArrivalTime.Equal(want) {
+		t.Errorf("want arrival %s, got %s", want, path[0].ArrivalTime)
+	}
+
+	// too far for reduced mobility, take the bus
+	path = g.FindRoute(a, b, at(t, "2026-10-16 08:00"), graph.ReducedMobilityProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"bus"}) {
+		t.Errorf("reduced mobility: want [bus], got %v", got)
+	}
+}
+
+func TestFindRoute_ExcludedModes(t *testing.T) {
+	g, a, b := walkOrRideGraph(t, 320)
+	profile := graph.ReducedMobilityProfile()
+	profile.ExcludedModes = map[graph.Mode]bool{graph.MODE_BUS: true}
+
+	if path := g.FindRoute(a, b, at(t, "2026-10-16 08:00"), profile); path != nil {
+		t.Errorf("want no route without walking or buses, got %v", tripsOf(path))
+	}
+}
+
+func TestFindRoute_WalkPenalty(t *testing.T) {
+	g, a, b := walkOrRideGraph(t, 320)
+	profile := graph.DefaultProfile()
+
+	// walking arrives 08:04 but costs 4 + 20 minutes, the bus costs 22 minutes
+	profile.WalkPenalty = 20 * 60
+	path := g.FindRoute(a, b, at(t, "2026-10-16 08:00"), profile)
+	if got := tripsOf(path); !slices.Equal(got, []string{"bus"}) {
+		t.Errorf("want [bus], got %v", got)
+	}
+}
+
+func TestProfileByName(t *testing.T) {
+	for _, name := range []string{"", "default", "reducedMobility", "luggage"} {
+		if _, ok := graph.ProfileByName(name); !ok {
+			t.Errorf("ProfileByName(%q) should exist", name)
+		}
+	}
+	if _, ok := graph.ProfileByName("rocket"); ok {
+		t.Error("ProfileByName(rocket) should not exist")
+	}
+}
@@ -0,0 +1,52 @@
+package graph_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+func TestFindRoutesInWindow(t *testing.T) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.31", "18.01")
+	addRide(t, g, a, b, "r0800", "", hms(8, 0), hms(8, 10))
+	addRide(t, g, a, b, "r0805", "", hms(8, 5), hms(8, 12))
+	// slow, dominated by r0820
+	addRide(t, g, a, b, "r0810", "", hms(8, 10), hms(8, 45))
+	addRide(t, g, a, b, "r0820", "", hms(8, 20), hms(8, 30))
+	addRide(t, g, a, b, "r0840", "", hms(8, 40), hms(8, 50))
+	// outside of the window
+	addRide(t, g, a, b, "r0910", "", hms(9, 10), hms(9, 20))
+
+	options := g.FindRoutesInWindow(a, b, at(t, "2026-10-16 08:00"), at(t, "2026-10-16 09:00"), graph.DefaultProfile())
+
+	var got []string
+	for _, o := range options {
+		got = append(got, tripsOf(o.Edges)...)
+	}
+	want := []string{"r0800", "r0805", "r0820", "r0840"}
+	if !slices.Equal(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestFindRoutesInWindow_WalkToFirstStop(t *testing.T) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.301", "18.001")
+	c := addStop(t, g, "C", "Gamma", "59.31", "18.01")
+	addWalk(t, g, a, b, 300)
+	addRide(t, g, b, c, "r1", "", hms(8, 10), hms(8, 20))
+	addRide(t, g, b, c, "r2", "", hms(8, 40), hms(8, 50))
+
+	options := g.FindRoutesInWindow(a, c, at(t, "2026-10-16 08:00"), at(t, "2026-10-16 09:00"), graph.DefaultProfile())
+	if len(options) != 2 {
+		t.Fatalf("want 2 options, got %d", len(options))
+	}
+	// leave A just in time to walk to B
+	if want := at(t, "2026-10-16 08:35"); !options[1].Departure.Equal(want) {
+		t.Errorf("want departure %s, got %s", want, options[1].Departure)
+	}
+}
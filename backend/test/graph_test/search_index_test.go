@@ -0,0 +1,23 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+func TestFindRoute_IndexFollowsChanges(t *testing.T) {
+	g, a, _, c, _ := multiGraph(t)
+	departure := at(t, "2026-10-16 08:00")
+	if route := g.FindRoute(a, c, departure, graph.DefaultProfile()); len(route) == 0 || tripsOf(route)[0] != "slow" {
+		t.Fatalf("want the slow trip, got %v", tripsOf(route))
+	}
+	addRide(t, g, a, c, "new", "", hms(8, 5), hms(8, 15))
+	if route := g.FindRoute(a, c, departure, graph.DefaultProfile()); len(route) == 0 || tripsOf(route)[0] != "new" {
+		t.Fatalf("want the added trip, got %v", tripsOf(route))
+	}
+	e := addStop(t, g, "E", "Epsilon", "59.50", "18.00")
+	if route := g.FindRoute(a, e, departure, graph.DefaultProfile()); route != nil {
+		t.Errorf("want no route to a stop without edges, got %v", tripsOf(route))
+	}
+}
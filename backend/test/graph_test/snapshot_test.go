@@ -0,0 +1,160 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// the fixture feed with a calendar and a transfer rule, so every part of the graph is set
+func snapshotFeedFiles() map[string]string {
+	files := make(map[string]string)
+	for name, content := range fixtureFeed {
+		files[name] = content
+	}
+	files["calendar.txt"] = "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+		"S1,1,1,1,1,1,0,0,20260101,20261231\n"
+	files["calendar_dates.txt"] = "service_id,date,exception_type\n" +
+		"S1,20261017,1\n"
+	files["transfers.txt"] = "from_stop_id,to_stop_id,transfer_type,min_transfer_time\n" +
+		"B,B,2,240\n"
+	return files
+}
+
+// helper: the edges of a graph in a comparable form
+func edgeSet(g *graph.SLGraph) []string {
+	var edges []string
+	for _, e := range g.AllEdges() {
+		info := graph.TripInfo{}
+		if e.Metadata.TripInfo != nil {
+			info = *e.Metadata.TripInfo
+		}
+		m := e.Metadata
+		m.TripInfo = nil
+		edges = append(edges, e.Source().StopID+">"+e.Destination().StopID+asJSON(m)+asJSON(info))
+	}
+	sort.Strings(edges)
+	return edges
+}
+
+func asJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	feed := loadFeed(t, snapshotFeedFiles())
+	checksum, err := feed.Checksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := graph.NewFromFeed(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := original.WriteSnapshot(&buf, checksum); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := graph.ReadSnapshot(&buf, checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Order() != original.Order() || loaded.Size() != original.Size() {
+		t.Fatalf("want order %d and size %d, got %d and %d", original.Order(), original.Size(), loaded.Order(), loaded.Size())
+	}
+	if !reflect.DeepEqual(edgeSet(loaded), edgeSet(original)) {
+		t.Errorf("edges differ:\n%v\n%v", edgeSet(loaded), edgeSet(original))
+	}
+	a, b := loaded.GetVertexByID("A"), loaded.GetVertexByID("B")
+	if stop := a.Metadata(); stop.StopName != "Alpha" || !stop.HasCoordinates() || stop.Lat != 59.3 {
+		t.Errorf("stop A: got %+v", stop)
+	}
+	if ab, bc := loaded.GetEdge(a, b, "T1"), loaded.GetEdge(b, loaded.GetVertexByID("C"), "T1"); ab.Metadata.TripInfo != bc.Metadata.TripInfo {
+		t.Error("edges of a trip should share their info after loading")
+	}
+	if loaded.Location().String() != "Europe/Stockholm" {
+		t.Errorf("want the feed timezone, got %s", loaded.Location())
+	}
+	// Saturday 17th is added by calendar_dates, Sunday 18th doesn't run
+	for day, want := range map[string]bool{"2026-10-16": true, "2026-10-17": true, "2026-10-18": false} {
+		if got := loaded.Calendar().IsActive("S1", date(t, day)); got != want {
+			t.Errorf("S1 on %s: want %v, got %v", day, want, got)
+		}
+	}
+	if rule := loaded.Transfers().Rule("B", "B"); rule == nil || rule.MinTransferTime != 240 {
+		t.Errorf("want the transfer rule at B, got %+v", rule)
+	}
+	if len(loaded.StopsWithinRadius(59.32, 18.0, 300)) != 2 {
+		t.Error("loaded stops should be in the spatial index")
+	}
+}
+
+func TestSnapshot_Invalid(t *testing.T) {
+	feed := loadFeed(t, snapshotFeedFiles())
+	checksum, _ := feed.Checksum()
+	g, err := graph.NewFromFeed(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := g.WriteSnapshot(&buf, checksum); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	other := checksum
+	other[0]++
+	if _, err := graph.ReadSnapshot(bytes.NewReader(data), other); !errors.Is(err, graph.ErrSnapshotStale) {
+		t.Errorf("other checksum: want ErrSnapshotStale, got %v", err)
+	}
+	if _, err := graph.ReadSnapshot(bytes.NewReader(data[:len(data)/2]), checksum); !errors.Is(err, graph.ErrSnapshotCorrupt) {
+		t.Errorf("truncated: want ErrSnapshotCorrupt, got %v", err)
+	}
+	if _, err := graph.ReadSnapshot(bytes.NewReader([]byte("not a snapshot")), checksum); !errors.Is(err, graph.ErrSnapshotCorrupt) {
+		t.Errorf("garbage: want ErrSnapshotCorrupt, got %v", err)
+	}
+	newer := bytes.Clone(data)
+	newer[len("SLGRAPH\n")] = graph.SNAPSHOT_VERSION + 1
+	if _, err := graph.ReadSnapshot(bytes.NewReader(newer), checksum); !errors.Is(err, graph.ErrSnapshotVersion) {
+		t.Errorf("other version: want ErrSnapshotVersion, got %v", err)
+	}
+}
+
+func TestBuild_RebuildsStaleSnapshot(t *testing.T) {
+	files := snapshotFeedFiles()
+	dir := writeFeedDir(t, files)
+	snapshot := filepath.Join(t.TempDir(), "sl.graph")
+
+	g, err := graph.Build(dir, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(snapshot); err != nil {
+		t.Fatalf("want a snapshot to be written, got %v", err)
+	}
+	cached, err := graph.Build(dir, snapshot)
+	if err != nil || cached.Size() != g.Size() {
+		t.Fatalf("want the graph from the snapshot, got %v", err)
+	}
+
+	// a new stop far away, the snapshot is stale
+	stops := files["stops.txt"] + "E,Epsilon,60.0000,18.0000,\n"
+	os.WriteFile(filepath.Join(dir, "stops.txt"), []byte(stops), 0o644)
+	rebuilt, err := graph.Build(dir, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt.GetVertexByID("E") == nil {
+		t.Error("want the graph rebuilt from the changed feed")
+	}
+}
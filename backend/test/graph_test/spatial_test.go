@@ -0,0 +1,92 @@
+package graph_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+func TestStopsWithinRadius_MatchesBruteForce(t *testing.T) {
+	g := graph.New()
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		// scatter stops over a few kilometers around Stockholm
+		lat := 59.30 + rng.Float64()*0.05
+		lon := 18.00 + rng.Float64()*0.10
+		addStop(t, g, fmt.Sprintf("S%03d", i), fmt.Sprintf("Stop %d", i), fmt.Sprintf("%f", lat), fmt.Sprintf("%f", lon))
+	}
+	// one stop without coordinates, it should never be returned
+	addStop(t, g, "NOCOORD", "Nowhere", "", "")
+
+	for _, radius := range []float64{100, 400, 1000} {
+		center := g.GetVertexByID("S000").Metadata()
+		nearby := g.StopsWithinRadius(center.Lat, center.Lon, radius)
+
+		want := 0
+		for _, v := range g.GetAllVertices() {
+			stop := v.Metadata()
+			if !stop.HasCoordinates() {
+				continue
+			}
+			if graph.DistanceMeters(center.Lat, center.Lon, stop.Lat, stop.Lon) <= radius {
+				want++
+			}
+		}
+		if len(nearby) != want {
+			t.Errorf("radius %.0f: want %d stops, got %d", radius, want, len(nearby))
+		}
+		for i, n := range nearby {
+			if n.Distance > radius {
+				t.Errorf("radius %.0f: %s is %.0f m away", radius, n.Stop.StopID, n.Distance)
+			}
+			if i > 0 && nearby[i-1].Distance > n.Distance {
+				t.Errorf("radius %.0f: results not sorted by distance", radius)
+			}
+		}
+		if len(nearby) == 0 || nearby[0].Stop.StopID != "S000" {
+			t.Errorf("radius %.0f: the center stop should come first", radius)
+		}
+	}
+}
+
+func TestStopsWithinRadius_RemovedVertex(t *testing.T) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.3300", "18.0600")
+	addStop(t, g, "B", "Beta", "59.3310", "18.0600")
+
+	if got := len(g.StopsWithinRadius(59.3305, 18.06, 200)); got != 2 {
+		t.Fatalf("want 2 stops, got %d", got)
+	}
+	g.RemoveVertices(a)
+	nearby := g.StopsWithinRadius(59.3305, 18.06, 200)
+	if len(nearby) != 1 || nearby[0].Stop.StopID != "B" {
+		t.Errorf("want only B after removing A, got %v", nearby)
+	}
+}
+
+func TestSetVertexMetadata_MovesStop(t *testing.T) {
+	g := graph.New()
+	v := graph.NewVertex("A")
+	if err := v.SetMetadata(&graph.Stop{StopID: "A", StopLatitude: "null", StopLongitude: "null"}); err == nil {
+		t.Error("want an error for invalid coordinates")
+	}
+	g.AddVertex(v)
+	if near := g.StopsWithinRadius(59.30, 18.00, 100); len(near) != 0 {
+		t.Fatalf("want no stops without coordinates, got %+v", near)
+	}
+	if err := g.SetVertexMetadata(v, &graph.Stop{StopID: "A", StopLatitude: "59.30", StopLongitude: "18.00"}); err != nil {
+		t.Fatal(err)
+	}
+	if near := g.StopsWithinRadius(59.30, 18.00, 100); len(near) != 1 || near[0].Vertex != v {
+		t.Fatalf("want A once it has coordinates, got %+v", near)
+	}
+	g.SetVertexMetadata(v, &graph.Stop{StopID: "A", StopLatitude: "59.40", StopLongitude: "18.00"})
+	if near := g.StopsWithinRadius(59.30, 18.00, 100); len(near) != 0 {
+		t.Errorf("want A moved away, got %+v", near)
+	}
+	if near := g.StopsWithinRadius(59.40, 18.00, 100); len(near) != 1 {
+		t.Errorf("want A at its new place, got %+v", near)
+	}
+}
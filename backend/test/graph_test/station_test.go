@@ -0,0 +1,184 @@
+package graph_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// a feed with stations: Slussen is a parent station with a metro and a bus
+// platform and an entrance, the Odenplan platforms have no parent and two of
+// them are close enough to be one station
+var stationFeed = map[string]string{
+	"agency.txt": "agency_id,agency_name,agency_url,agency_timezone,agency_lang\n" +
+		"1,SL,http://sl.se,Europe/Stockholm,sv\n",
+	"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+		"ST1,Slussen,59.3195,18.0720,1,\n" +
+		"S1,Slussen,59.3195,18.0718,0,ST1\n" +
+		"S2,Slussen,59.3197,18.0725,0,ST1\n" +
+		"E1,Slussen entré,59.3190,18.0715,2,ST1\n" +
+		"G1,Gamla stan,59.3230,18.0670,,\n" +
+		"M1,Medborgarplatsen,59.3142,18.0735,,\n" +
+		"O1,Odenplan,59.3430,18.0500,,\n" +
+		"O2,Odenplan,59.3435,18.0510,,\n" +
+		"O3,Odenplan,59.3900,18.0500,,\n",
+	"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type,route_url\n" +
+		"R1,1,13,,401,\n" +
+		"R2,1,2,,700,\n",
+	"trips.txt": "route_id,service_id,trip_id,trip_headsign,trip_short_name\n" +
+		"R1,S,T1,Ropsten,\n" +
+		"R2,S,T2,Sofia,\n" +
+		"R1,S,T3,Odenplan,\n",
+	"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence,pickup_type,drop_off_type\n" +
+		"T1,08:00:00,08:00:00,S1,1,0,0\n" +
+		"T1,08:03:00,08:03:00,G1,2,0,0\n" +
+		"T2,08:02:00,08:02:00,S2,1,0,0\n" +
+		"T2,08:06:00,08:06:00,M1,2,0,0\n" +
+		"T3,08:10:00,08:10:00,S1,1,0,0\n" +
+		"T3,08:20:00,08:20:00,O2,2,0,0\n",
+}
+
+func stationGraph(t *testing.T) *graph.SLGraph {
+	t.Helper()
+	g, err := graph.NewFromFeed(loadFeed(t, stationFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func platformIDs(s *graph.Station) []string {
+	ids := []string{}
+	for _, stop := range s.Platforms {
+		ids = append(ids, stop.StopID)
+	}
+	return ids
+}
+
+func TestStations_ParentStation(t *testing.T) {
+	g := stationGraph(t)
+	if g.GetVertexByID("ST1") != nil || g.GetVertexByID("E1") != nil {
+		t.Error("stations and entrances aren't boarded, they shouldn't be vertices")
+	}
+	s := g.GetStation("ST1")
+	if s == nil {
+		t.Fatal("want the parent station ST1")
+	}
+	// S1 has two departures, S2 one
+	if ids := platformIDs(s); len(ids) != 2 || ids[0] != "S1" || ids[1] != "S2" {
+		t.Errorf("want platforms S1 and S2, busiest first, got %v", ids)
+	}
+	if s.Lat != 59.3195 || s.Lon != 18.0720 {
+		t.Errorf("want the coordinate of the parent, got %f,%f", s.Lat, s.Lon)
+	}
+	if g.StationOf(g.GetVertexByID("S2")) != s {
+		t.Error("S2 should be a platform of ST1")
+	}
+}
+
+func TestStations_GroupedByName(t *testing.T) {
+	g := stationGraph(t)
+	near := g.StationOf(g.GetVertexByID("O1"))
+	if near == nil || near.ID != graph.STATION_ID_PREFIX+"O1" || len(near.Platforms) != 2 {
+		t.Fatalf("want O1 and O2 as one station, got %+v", near)
+	}
+	if g.StationOf(g.GetVertexByID("O2")) != near {
+		t.Error("O2 should be in the station of O1")
+	}
+	// O3 is 5 km away, a station of its own keeping its stop ID
+	far := g.StationOf(g.GetVertexByID("O3"))
+	if far == nil || far.ID != "O3" || len(far.Platforms) != 1 {
+		t.Errorf("want O3 alone, got %+v", far)
+	}
+	if g.GetStation(near.ID) != near {
+		t.Error("GetStation should find the grouped station by its ID")
+	}
+}
+
+func TestSearchStations(t *testing.T) {
+	g := stationGraph(t)
+	got := g.SearchStations("slussen", 0)
+	if len(got) != 1 || got[0].ID != "ST1" {
+		t.Fatalf("want only the station ST1, got %v", got)
+	}
+	odenplan := g.SearchStations("odenplan", 0)
+	if len(odenplan) != 2 {
+		t.Errorf("want the two Odenplan stations, got %d", len(odenplan))
+	}
+	if got := g.SearchStations("odenplan", 1); len(got) != 1 {
+		t.Errorf("limit 1: got %d", len(got))
+	}
+	// the stop search returns the platforms of the stations
+	if got := labels(g.SearchStops("slussen", 0)); len(got) != 2 || got[0] != "S1" {
+		t.Errorf("want the platforms of Slussen, got %v", got)
+	}
+}
+
+func TestFindRouteBetween_Station(t *testing.T) {
+	g := stationGraph(t)
+	slussen := graph.StationPlace(g.GetStation("ST1"))
+	departure := at(t, "2026-10-16 07:55")
+
+	// only the bus platform reaches Medborgarplatsen
+	path := g.FindRouteBetween(slussen, graph.StopPlace(g.GetVertexByID("M1")), departure, graph.DefaultProfile())
+	if len(path) != 1 || path[0].Edge.Source().StopID != "S2" || path[0].Edge.Metadata.TripID != "T2" {
+		t.Fatalf("want the bus from S2, got %v", path)
+	}
+
+	// to a station, arriving at any of its platforms
+	odenplan := graph.StationPlace(g.StationOf(g.GetVertexByID("O1")))
+	path = g.FindRouteBetween(slussen, odenplan, departure, graph.DefaultProfile())
+	if len(path) != 1 || path[0].Edge.Destination().StopID != "O2" {
+		t.Fatalf("want the metro to O2 without the connections to the stations, got %v", path)
+	}
+	if journey := graph.NewJourney(path); journey.WalkMeters != 0 || len(journey.Legs) != 1 {
+		t.Errorf("want a single ride, got %+v", journey)
+	}
+}
+
+func TestSnapshot_Stations(t *testing.T) {
+	g := stationGraph(t)
+	var buf bytes.Buffer
+	checksum := sha256.Sum256([]byte("stations"))
+	if err := g.WriteSnapshot(&buf, checksum); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := graph.ReadSnapshot(&buf, checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := loaded.GetStation("ST1")
+	if s == nil || s.Name != "Slussen" || len(s.Platforms) != 2 {
+		t.Fatalf("want the station after loading the snapshot, got %+v", s)
+	}
+	if s.Platforms[0].ParentStation != "ST1" {
+		t.Errorf("want the parent station of the platforms, got %q", s.Platforms[0].ParentStation)
+	}
+}
+
+func TestStationPlaces_ParetoWindowAndArriveBy(t *testing.T) {
+	g := stationGraph(t)
+	slussen := graph.StationPlace(g.GetStation("ST1"))
+	odenplan := graph.StationPlace(g.StationOf(g.GetVertexByID("O1")))
+	medborgarplatsen := graph.StopPlace(g.GetVertexByID("M1"))
+
+	options := g.FindParetoRoutesBetween(slussen, odenplan, at(t, "2026-10-16 07:55"), graph.DefaultProfile())
+	if len(options) != 1 || len(options[0].Edges) != 1 || options[0].Edges[0].Edge.Destination().StopID != "O2" {
+		t.Fatalf("want the metro from S1 to O2, got %+v", options)
+	}
+	// only the bus platform reaches Medborgarplatsen
+	options = g.FindRoutesInWindowBetween(slussen, medborgarplatsen, at(t, "2026-10-16 07:00"), at(t, "2026-10-16 09:00"), graph.DefaultProfile())
+	if len(options) != 1 || options[0].Edges[0].Edge.Source().StopID != "S2" {
+		t.Fatalf("want the bus from S2, got %+v", options)
+	}
+	path := g.FindRouteArriveByBetween(slussen, odenplan, at(t, "2026-10-16 08:30"), graph.DefaultProfile())
+	if len(path) != 1 || path[0].Edge.Metadata.TripID != "T3" {
+		t.Fatalf("want the metro leaving S1 at 08:10, got %v", tripsOf(path))
+	}
+	// coordinates have no platforms to start or end at
+	if options := g.FindParetoRoutesBetween(graph.CoordinatePlace(59.3195, 18.0720), odenplan, at(t, "2026-10-16 07:55"), graph.DefaultProfile()); options != nil {
+		t.Errorf("want no options from a coordinate, got %+v", options)
+	}
+}
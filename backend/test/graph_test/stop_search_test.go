@@ -0,0 +1,114 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+)
+
+// helper: a graph of stops, busy ones get more departures
+func stopSearchGraph(t *testing.T) *graph.SLGraph {
+	t.Helper()
+	g := graph.New()
+	stops := []struct {
+		id, name   string
+		departures int
+	}{
+		{"1", "T-Centralen", 6},
+		{"2", "Centralplan", 1},
+		{"3", "Södermalmstorg", 1},
+		{"4", "Slussen", 4},
+		{"5", "Slussen", 1},
+		{"6", "Solna station", 2},
+		{"7", "Solna centrum", 3},
+		{"8", "Upplands Väsby station", 1},
+		{"9", "Sundbyberg", 1},
+	}
+	sink := addStop(t, g, "0", "Depå", "59.0", "18.0")
+	for _, s := range stops {
+		v := addStop(t, g, s.id, s.name, "59.3", "18.0")
+		for i := 0; i < s.departures; i++ {
+			addRide(t, g, v, sink, s.id+"-"+string(rune('a'+i)), "", hms(8, i), hms(9, 0))
+		}
+	}
+	return g
+}
+
+func labels(vertices []*graph.Vertex) []string {
+	out := []string{}
+	for _, v := range vertices {
+		out = append(out, v.Label())
+	}
+	return out
+}
+
+func TestSearchStops(t *testing.T) {
+	g := stopSearchGraph(t)
+	tests := []struct {
+		query string
+		first string
+	}{
+		{"sodermalm", "3"},      // diacritics folded
+		{"SÖDERMALMSTORG", "3"}, // case
+		{"t-centralen", "1"},    // punctuation
+		{"t centralen", "1"},    // punctuation
+		{"central", "1"},        // both start a word, T-Centralen is busier
+		{"centralp", "2"},       // only one matches
+		{"vasby", "8"},          // a word in the middle
+		{"slusen", "4"},         // a missing letter, the busier platform first
+		{"solan", "7"},          // swapped letters, Solna centrum is busier
+		{"solna stat", "6"},     // words cut short while typing
+		{"station solna", "6"},  // any word order
+		{"upplands vasbi", "8"}, // a typo in the last word
+		{"malmstorg", "3"},      // inside a word
+		{"sundbyberg", "9"},     // exact
+	}
+	for _, tt := range tests {
+		got := g.SearchStops(tt.query, 0)
+		if len(got) == 0 || got[0].Label() != tt.first {
+			t.Errorf("%q: want %s first, got %v", tt.query, tt.first, labels(got))
+		}
+	}
+}
+
+func TestSearchStops_Ranking(t *testing.T) {
+	g := stopSearchGraph(t)
+	// exact before prefix before fuzzy
+	got := labels(g.SearchStops("slussen", 0))
+	if len(got) != 2 || got[0] != "4" || got[1] != "5" {
+		t.Errorf("want both Slussen platforms, busiest first, got %v", got)
+	}
+	got = labels(g.SearchStops("solna", 0))
+	if len(got) != 2 || got[0] != "7" || got[1] != "6" {
+		t.Errorf("want Solna centrum then Solna station, got %v", got)
+	}
+}
+
+func TestSearchStops_Limit(t *testing.T) {
+	g := stopSearchGraph(t)
+	if got := g.SearchStops("s", 0); len(got) != 7 {
+		t.Errorf("want every stop with a word starting with s, got %v", labels(got))
+	}
+	if got := g.SearchStops("s", 2); len(got) != 2 {
+		t.Errorf("want 2 stops, got %v", labels(got))
+	}
+	if got := g.SearchStops(" - ", 0); len(got) != 0 {
+		t.Errorf("want nothing for an empty query, got %v", labels(got))
+	}
+	if got := g.SearchStops("xyzzy", 0); len(got) != 0 {
+		t.Errorf("want nothing, got %v", labels(got))
+	}
+}
+
+func TestSearchStops_SeesChanges(t *testing.T) {
+	g := stopSearchGraph(t)
+	g.SearchStops("slussen", 0)
+	v := addStop(t, g, "10", "Skanstull", "59.3", "18.0")
+	if got := g.SearchStops("skanstull", 0); len(got) != 1 {
+		t.Errorf("want the added stop, got %v", labels(got))
+	}
+	g.RemoveVertices(v)
+	if got := g.SearchStops("skanstull", 0); len(got) != 0 {
+		t.Errorf("want the removed stop gone, got %v", labels(got))
+	}
+}
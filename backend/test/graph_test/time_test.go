@@ -0,0 +1,86 @@
+package graph_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Durelius/next-week/internal/graph"
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+func TestServiceDayStart_DST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	// clocks go forward 02:00 -> 03:00 on 2026-03-29, so noon minus 12h is 23:00 the day before
+	got := graph.ServiceDayStart(time.Date(2026, 3, 29, 15, 0, 0, 0, loc))
+	want := time.Date(2026, 3, 28, 23, 0, 0, 0, loc)
+	if !got.Equal(want) {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	got = graph.ServiceDayStart(time.Date(2026, 10, 16, 15, 0, 0, 0, loc))
+	want = time.Date(2026, 10, 16, 0, 0, 0, 0, loc)
+	if !got.Equal(want) {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func overnightGraph(t *testing.T) (*graph.SLGraph, *graph.Vertex, *graph.Vertex, *graph.Vertex) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.31", "18.01")
+	c := addStop(t, g, "C", "Gamma", "59.32", "18.02")
+	// night trip belongs to the previous service day, 24:20 -> 24:35
+	addRide(t, g, a, b, "night", "daily", hms(24, 20), hms(24, 35))
+	// first morning trip onwards
+	addRide(t, g, b, c, "morning", "daily", hms(5, 30), hms(5, 45))
+	g.SetCalendar(graph.NewServiceCalendar([]*gtfs.Calendar{
+		{ServiceID: "daily", Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true, Saturday: true, Sunday: true, StartDate: feedDate("20260101"), EndDate: feedDate("20261231")},
+	}, nil))
+	return g, a, b, c
+}
+
+func TestFindRoute_ContinuesPastMidnight(t *testing.T) {
+	g, a, _, c := overnightGraph(t)
+
+	path := g.FindRoute(a, c, at(t, "2026-10-16 23:50"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"night", "morning"}) {
+		t.Fatalf("want [night morning], got %v", got)
+	}
+	if want := at(t, "2026-10-17 00:20"); !path[0].DepartureTime.Equal(want) {
+		t.Errorf("night departure: want %s, got %s", want, path[0].DepartureTime)
+	}
+	if want := at(t, "2026-10-17 05:45"); !path[1].ArrivalTime.Equal(want) {
+		t.Errorf("morning arrival: want %s, got %s", want, path[1].ArrivalTime)
+	}
+}
+
+func TestFindRoute_AfterMidnightUsesPreviousServiceDay(t *testing.T) {
+	g, a, b, _ := overnightGraph(t)
+
+	// 00:10 on saturday still catches friday's 24:20 trip
+	path := g.FindRoute(a, b, at(t, "2026-10-17 00:10"), graph.DefaultProfile())
+	if len(path) != 1 {
+		t.Fatalf("want one edge, got %d", len(path))
+	}
+	if want := at(t, "2026-10-17 00:20"); !path[0].DepartureTime.Equal(want) {
+		t.Errorf("want departure %s, got %s", want, path[0].DepartureTime)
+	}
+}
+
+func TestFindRoute_KeepsSeconds(t *testing.T) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.31", "18.01")
+	addRide(t, g, a, b, "t1", "", hms(8, 0)+30, hms(8, 4)+45)
+
+	path := g.FindRoute(a, b, at(t, "2026-10-16 08:00"), graph.DefaultProfile())
+	if len(path) != 1 {
+		t.Fatalf("want one edge, got %d", len(path))
+	}
+	if want := at(t, "2026-10-16 08:04").Add(45 * time.Second); !path[0].ArrivalTime.Equal(want) {
+		t.Errorf("want arrival %s, got %s", want, path[0].ArrivalTime)
+	}
+}
@@ -0,0 +1,101 @@
+package graph_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/graph"
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// A -> B arriving 08:10, B -> C leaving 08:10 or 08:20
+func changeGraph(t *testing.T) (*graph.SLGraph, *graph.Vertex, *graph.Vertex) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.31", "18.01")
+	c := addStop(t, g, "C", "Gamma", "59.32", "18.02")
+	addRide(t, g, a, b, "in", "", hms(8, 0), hms(8, 10))
+	addRide(t, g, b, c, "same", "", hms(8, 10), hms(8, 15))
+	addRide(t, g, b, c, "later", "", hms(8, 20), hms(8, 25))
+	return g, a, c
+}
+
+func TestFindRoute_DefaultMinChangeTime(t *testing.T) {
+	g, a, c := changeGraph(t)
+
+	path := g.FindRoute(a, c, at(t, "2026-10-16 07:55"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"in", "later"}) {
+		t.Errorf("want [in later], got %v", got)
+	}
+	options := g.FindParetoRoutes(a, c, at(t, "2026-10-16 07:55"), graph.DefaultProfile())
+	if len(options) != 1 || !slices.Equal(tripsOf(options[0].Edges), []string{"in", "later"}) {
+		t.Errorf("pareto: want a single [in later] option, got %d options", len(options))
+	}
+	path = g.FindRouteArriveBy(a, c, at(t, "2026-10-16 08:30"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"in", "later"}) {
+		t.Errorf("arrive by: want [in later], got %v", got)
+	}
+}
+
+func TestFindRoute_TimedTransfer(t *testing.T) {
+	g, a, c := changeGraph(t)
+	g.SetTransfers(graph.NewTransferRules([]*gtfs.Transfer{
+		{FromStopID: "B", ToStopID: "B", TransferType: gtfs.TRANSFER_TIMED},
+	}))
+
+	path := g.FindRoute(a, c, at(t, "2026-10-16 07:55"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"in", "same"}) {
+		t.Errorf("want [in same], got %v", got)
+	}
+}
+
+func TestFindRoute_StopMinTransferTime(t *testing.T) {
+	g, a, c := changeGraph(t)
+	g.SetTransfers(graph.NewTransferRules([]*gtfs.Transfer{
+		{FromStopID: "B", ToStopID: "B", TransferType: gtfs.TRANSFER_MIN_TIME, MinTransferTime: 15 * 60},
+	}))
+
+	// nothing leaves B 15 minutes after arriving until the next morning
+	path := g.FindRoute(a, c, at(t, "2026-10-16 07:55"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"in", "same"}) {
+		t.Fatalf("want [in same], got %v", got)
+	}
+	if want := at(t, "2026-10-17 08:10"); !path[1].DepartureTime.Equal(want) {
+		t.Errorf("want departure %s, got %s", want, path[1].DepartureTime)
+	}
+}
+
+func TestFindRoute_ForbiddenTransfer(t *testing.T) {
+	g, a, c := changeGraph(t)
+	g.SetTransfers(graph.NewTransferRules([]*gtfs.Transfer{
+		{FromStopID: "B", ToStopID: "B", TransferType: gtfs.TRANSFER_NOT_POSSIBLE},
+	}))
+
+	if path := g.FindRoute(a, c, at(t, "2026-10-16 07:55"), graph.DefaultProfile()); path != nil {
+		t.Errorf("want no route, got %v", tripsOf(path))
+	}
+}
+
+func TestFindRoute_WalkingTransferTime(t *testing.T) {
+	g := graph.New()
+	a := addStop(t, g, "A", "Alpha", "59.30", "18.00")
+	b := addStop(t, g, "B", "Beta", "59.30", "18.001")
+	c := addStop(t, g, "C", "Gamma", "59.32", "18.02")
+	addWalk(t, g, a, b, 60)
+	addRide(t, g, b, c, "early", "", hms(8, 2), hms(8, 10))
+	addRide(t, g, b, c, "late", "", hms(8, 12), hms(8, 20))
+
+	path := g.FindRoute(a, c, at(t, "2026-10-16 08:00"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"", "early"}) {
+		t.Fatalf("want [ early], got %v", got)
+	}
+
+	// the feed says walking from A to B takes 10 minutes
+	g.SetTransfers(graph.NewTransferRules([]*gtfs.Transfer{
+		{FromStopID: "A", ToStopID: "B", TransferType: gtfs.TRANSFER_MIN_TIME, MinTransferTime: 10 * 60},
+	}))
+	path = g.FindRoute(a, c, at(t, "2026-10-16 08:00"), graph.DefaultProfile())
+	if got := tripsOf(path); !slices.Equal(got, []string{"", "late"}) {
+		t.Errorf("want [ late], got %v", got)
+	}
+}
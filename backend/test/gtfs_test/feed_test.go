@@ -0,0 +1,111 @@
+package gtfs_test
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+type stop struct {
+	StopID   string `csv:"stop_id"`
+	StopName string `csv:"stop_name"`
+}
+
+var feedFiles = map[string]string{
+	"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n1,SL,http://sl.se,Europe/Stockholm\n",
+	"stops.txt":  "stop_id,stop_name,stop_lat,stop_lon\nA,Alpha,59.30,18.00\nB,Beta,59.31,18.00\n",
+}
+
+// helper: write files into a new directory
+func writeDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// helper: write files into a zip archive, below prefix
+func writeZip(t *testing.T, prefix string, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "feed.zip")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	w := zip.NewWriter(out)
+	for name, content := range files {
+		f, err := w.Create(prefix + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Sources(t *testing.T) {
+	sources := map[string]string{
+		"directory":  writeDir(t, feedFiles),
+		"zip":        writeZip(t, "", feedFiles),
+		"nested zip": writeZip(t, "sl/", feedFiles),
+		"SL names": writeDir(t, map[string]string{
+			"sl_agency.csv": feedFiles["agency.txt"],
+			"sl_stops.csv":  feedFiles["stops.txt"],
+		}),
+	}
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			feed, err := gtfs.Load(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer feed.Close()
+			var stops []*stop
+			if err := feed.Unmarshal(gtfs.FILE_STOPS, &stops); err != nil {
+				t.Fatal(err)
+			}
+			if len(stops) != 2 || stops[1].StopName != "Beta" {
+				t.Errorf("want stops Alpha and Beta, got %v", stops)
+			}
+		})
+	}
+}
+
+func TestLoad_MissingFiles(t *testing.T) {
+	feed, err := gtfs.Load(writeDir(t, feedFiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stops []*stop
+	if err := feed.Unmarshal(gtfs.FILE_STOP_TIMES, &stops); !errors.Is(err, gtfs.ErrMissingFile) {
+		t.Errorf("required file: want ErrMissingFile, got %v", err)
+	}
+	if err := feed.UnmarshalOptional(gtfs.FILE_TRANSFERS, &stops); err != nil || stops != nil {
+		t.Errorf("optional file: want no error and nothing read, got %v, %v", err, stops)
+	}
+	if feed.Has(gtfs.FILE_CALENDAR) || !feed.Has(gtfs.FILE_AGENCY) {
+		t.Error("Has reports the wrong files")
+	}
+}
+
+func TestLoad_InvalidSource(t *testing.T) {
+	if _, err := gtfs.Load(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("want an error for a missing source")
+	}
+	notZip := filepath.Join(writeDir(t, feedFiles), "stops.txt")
+	if _, err := gtfs.Load(notZip); err == nil {
+		t.Error("want an error for a file that isn't a zip")
+	}
+}
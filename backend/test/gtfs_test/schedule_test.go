@@ -0,0 +1,160 @@
+package gtfs_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+// a valid feed using SL style null values, every test breaks one thing
+func validFeed() map[string]string {
+	return map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone,agency_lang\n" +
+			"275,SL,http://www.sl.se,Europe/Stockholm,sv\n",
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+			"S,Solna station,59.365103,18.010037,1,\n" +
+			"A,Solna station,59.365103,18.010037,null,S\n" +
+			"B,Mörby centrum,59.398709,18.036220,null,\n",
+		"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type,route_url\n" +
+			"R,275,14,,401,\n",
+		"trips.txt": "route_id,service_id,trip_id,trip_headsign,trip_short_name\n" +
+			"R,WK,T1,Mörby centrum,null\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence,pickup_type,drop_off_type\n" +
+			"T1,23:55:00,23:56:00,A,1,0,0\n" +
+			"T1,24:10:00,24:10:00,B,2,0,0\n",
+		"calendar.txt": "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+			"WK,1,1,1,1,1,0,0,20260101,20261231\n",
+		"frequencies.txt": "trip_id,start_time,end_time,headway_secs,exact_times\n" +
+			"T1,06:00:00,09:00:00,600,1\n",
+	}
+}
+
+// helper: read the schedule of a feed
+func readSchedule(t *testing.T, files map[string]string) *gtfs.Schedule {
+	t.Helper()
+	feed, err := gtfs.Load(writeDir(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Close()
+	s, err := feed.ReadSchedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestReadSchedule_TypedFields(t *testing.T) {
+	s := readSchedule(t, validFeed())
+
+	if len(s.Stops) != 3 || s.Stops[0].LocationType != gtfs.LOCATION_STATION || s.Stops[1].LocationType != gtfs.LOCATION_STOP {
+		t.Fatalf("stops: got %+v", s.Stops)
+	}
+	if s.Stops[2].StopLat != 59.398709 || s.Stops[2].StopLon != 18.036220 {
+		t.Errorf("coordinates: got %f, %f", s.Stops[2].StopLat, s.Stops[2].StopLon)
+	}
+	if r := s.Routes[0].RouteType; r != 401 || r.Basic() != gtfs.ROUTE_TYPE_SUBWAY {
+		t.Errorf("route type: want 401 (subway), got %d (%d)", r, r.Basic())
+	}
+	if got := s.StopTimes[1].ArrivalTime; got != 24*3600+10*60 || got.String() != "24:10:00" {
+		t.Errorf("times past midnight: got %d (%s)", got, got)
+	}
+	c := s.Calendars[0]
+	if !bool(c.Monday) || bool(c.Saturday) || !c.StartDate.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("calendar: got %+v", c)
+	}
+	if f := s.Frequencies[0]; f.HeadwaySecs != 600 || f.ExactTimes != gtfs.SCHEDULE_BASED || f.StartTime.String() != "06:00:00" {
+		t.Errorf("frequency: got %+v", f)
+	}
+	if len(s.Transfers) != 0 || len(s.Shapes) != 0 {
+		t.Error("missing optional files should be empty")
+	}
+}
+
+func TestReadSchedule_InvalidValue(t *testing.T) {
+	files := validFeed()
+	files["stop_times.txt"] = strings.Replace(files["stop_times.txt"], "23:55:00", "23:5", 1)
+	feed, err := gtfs.Load(writeDir(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := feed.ReadSchedule(); err == nil {
+		t.Error("want an error for an invalid time")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := map[string]gtfs.Time{
+		"00:00:00": 0,
+		"8:05:30":  8*3600 + 5*60 + 30,
+		"25:00:00": 25 * 3600,
+	}
+	for value, want := range tests {
+		if got, err := gtfs.ParseTime(value); err != nil || got != want {
+			t.Errorf("ParseTime(%s): want %d, got %d (%v)", value, want, got, err)
+		}
+	}
+	for _, value := range []string{"", "08:00", "08:60:00", "08:5:00", "ab:00:00"} {
+		if _, err := gtfs.ParseTime(value); err == nil {
+			t.Errorf("ParseTime(%q): want an error", value)
+		}
+	}
+}
+
+func TestValidate_ValidFeed(t *testing.T) {
+	if problems := readSchedule(t, validFeed()).Validate(); len(problems) != 0 {
+		t.Errorf("want no problems, got %v", problems)
+	}
+}
+
+func TestValidate_Problems(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		from    string
+		to      string
+		problem string
+	}{
+		{"unknown stop", "stop_times.txt", "T1,24:10:00,24:10:00,B", "T1,24:10:00,24:10:00,X", `stop_times.txt:3: stop_id "X" does not exist`},
+		{"unknown trip", "stop_times.txt", "T1,24:10:00,24:10:00,B,2", "T2,24:10:00,24:10:00,B,2", `stop_times.txt:3: trip_id "T2" does not exist`},
+		{"unknown route", "trips.txt", "R,WK", "Q,WK", `trips.txt:2: route_id "Q" does not exist`},
+		{"unknown service", "trips.txt", "R,WK", "R,SUN", `trips.txt:2: service_id "SUN" does not exist`},
+		{"unknown parent", "stops.txt", "null,S", "null,Z", `stops.txt:3: parent_station "Z" does not exist`},
+		{"duplicate stop", "stops.txt", "B,Mörby", "A,Mörby", `stops.txt:4: duplicate stop_id "A"`},
+		{"back in time", "stop_times.txt", "T1,24:10:00,24:10:00", "T1,23:50:00,23:50:00", "stop_times.txt:3: trip \"T1\" arrives at 23:50:00, before leaving the previous stop at 23:56:00"},
+		{"departs before arriving", "stop_times.txt", "23:55:00,23:56:00", "23:57:00,23:56:00", "stop_times.txt:2: departure_time 23:56:00 before arrival_time 23:57:00"},
+		{"duplicate sequence", "stop_times.txt", "B,2", "B,1", `stop_times.txt:3: duplicate stop_sequence 1 in trip "T1"`},
+		{"frequency of unknown trip", "frequencies.txt", "T1,06", "T9,06", `frequencies.txt:2: trip_id "T9" does not exist`},
+		{"empty frequency window", "frequencies.txt", "09:00:00", "05:00:00", "frequencies.txt:2: end_time 05:00:00 is not after start_time 06:00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := validFeed()
+			if !strings.Contains(files[tt.file], tt.from) {
+				t.Fatalf("%s doesn't contain %q", tt.file, tt.from)
+			}
+			files[tt.file] = strings.Replace(files[tt.file], tt.from, tt.to, 1)
+			problems := readSchedule(t, files).Validate()
+			for _, p := range problems {
+				if p.Error() == tt.problem {
+					return
+				}
+			}
+			t.Errorf("want %q, got %v", tt.problem, problems)
+		})
+	}
+}
+
+func TestValidate_TransferOfTrip(t *testing.T) {
+	files := validFeed()
+	files["transfers.txt"] = "from_stop_id,to_stop_id,from_trip_id,to_trip_id,transfer_type,min_transfer_time\n" +
+		"A,B,,,2,300\n" +
+		"B,B,T1,T1,1,\n"
+	problems := readSchedule(t, files).Validate()
+	want := "transfers.txt:3: transfers of a route or trip are not supported, the row is ignored"
+	if len(problems) != 1 || problems[0].Error() != want {
+		t.Errorf("want %q, got %v", want, problems)
+	}
+}
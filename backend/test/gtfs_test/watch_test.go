@@ -0,0 +1,34 @@
+package gtfs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Durelius/next-week/internal/gtfs"
+)
+
+func TestWatch_ReportsChange(t *testing.T) {
+	dir := writeDir(t, map[string]string{"stops.txt": "stop_id,stop_name\nA,Alpha\n"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan struct{}, 1)
+	go gtfs.Watch(ctx, dir, 10*time.Millisecond, func() {
+		changes <- struct{}{}
+	})
+
+	select {
+	case <-changes:
+		t.Fatal("want no change reported for an untouched feed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	changed := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "stops.txt"), changed, changed)
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("want the change reported")
+	}
+}
@@ -7,59 +7,118 @@ interface Stop {
   stopLat: string;
   stopLon: string;
   locationType: string;
+  parentStation?: string;
 }
 
-interface EdgeMetadata {
-  tripId: string;
+// the platforms sharing a name, routing may use any of them
+interface Station {
+  id: string;
+  name: string;
+  lat: number;
+  lon: number;
+  platforms: Stop[];
+}
+
+// the line of a ride, absent for walks
+interface LineInfo {
   routeId?: string;
-  serviceId?: string;
+  routeShortName?: string; // line number
   tripHeadsign?: string;
   tripShortName?: string;
-  departure: number;
-  arrival: number;
-  transferType?: number; // 1 = walking transfer, 2 = transit
-  sourceStopName: string;
-  destStopName: string;
+  agencyName?: string;
+  mode?: "tram" | "metro" | "rail" | "bus" | "ferry" | "unknown";
+}
+
+interface LegStop {
+  stop: Stop;
+  arrival: string; // RFC 3339
+  departure: string;
+}
+
+interface Leg extends LineInfo {
+  transferType: number; // 1 = walking, 2 = transit
+  tripId?: string;
+  from: Stop;
+  to: Stop;
+  departure: string; // RFC 3339
+  arrival: string;
+  intermediateStops: LegStop[];
+  durationSeconds: number;
+  distance?: number; // meters, for walks
 }
 
-interface Edge {
-  source?: string;
-  destination?: string;
-  metadata: EdgeMetadata;
+interface Journey {
+  legs: Leg[];
+  departure: string; // RFC 3339
+  arrival: string;
+  durationSeconds: number;
+  transfers: number;
+  walkSeconds: number;
+  walkMeters: number;
 }
 
 // --- API ---
-const API_BASE = "http://localhost:8080";
+const API_BASE = "http://localhost:8080/api/v1";
+
+// every failed request answers with an error object
+interface ApiError {
+  error: { code: string; message: string };
+}
+
+async function apiError(res: Response, fallback: string): Promise<Error> {
+  try {
+    const body: ApiError = await res.json();
+    return new Error(body.error.message);
+  } catch {
+    return new Error(fallback);
+  }
+}
 
-async function fetchStopsByName(name: string): Promise<Stop[]> {
+async function fetchStationsByName(name: string): Promise<Station[]> {
   if (!name.trim()) return [];
-  const res = await fetch(`${API_BASE}/stopbyname/${encodeURIComponent(name)}`);
-  if (!res.ok) throw new Error("Failed to fetch stops");
+  const res = await fetch(`${API_BASE}/stations?${new URLSearchParams({ name })}`);
+  if (!res.ok) throw await apiError(res, "Failed to fetch stations");
   return res.json();
 }
 
-async function fetchRoute(fromId: string, toId: string, time: string): Promise<Edge[]> {
-  const res = await fetch(`${API_BASE}/path/${fromId}/${toId}/${encodeURIComponent(time)}`);
-  if (!res.ok) throw new Error("Failed to fetch route");
+async function fetchRoute(fromId: string, toId: string, time: string): Promise<Journey | null> {
+  const res = await fetch(`${API_BASE}/journey?${new URLSearchParams({ from: fromId, to: toId, time })}`);
+  if (res.status === 404) {
+    const body: ApiError = await res.json();
+    if (body.error.code === "no_route") return null;
+    throw new Error(body.error.message);
+  }
+  if (!res.ok) throw await apiError(res, "Failed to fetch route");
   return res.json();
 }
 
-function minutesToTime(minutes: number): string {
-  const h = Math.floor(minutes / 60) % 24;
-  const m = minutes % 60;
-  return `${String(h).padStart(2, "0")}:${String(m).padStart(2, "0")}`;
+function formatTime(time: string): string {
+  const d = new Date(time);
+  return `${String(d.getHours()).padStart(2, "0")}:${String(d.getMinutes()).padStart(2, "0")}`;
 }
 
-function travelDuration(dep: number, arr: number): string {
-  const diff = arr - dep;
+function travelDuration(dep: string, arr: string): string {
+  const diff = Math.round((new Date(arr).getTime() - new Date(dep).getTime()) / 60000);
   if (diff <= 0) return "–";
   const h = Math.floor(diff / 60);
   const m = diff % 60;
   return h > 0 ? `${h} h ${m} min` : `${m} min`;
 }
 
-function isTransferEdge(edge: Edge): boolean {
-  return edge.metadata.transferType === 1 || !edge.metadata.tripId;
+const MODE_LABELS: Record<string, string> = {
+  tram: "Spårvagn",
+  metro: "Tunnelbana",
+  rail: "Pendeltåg",
+  bus: "Buss",
+  ferry: "Båt",
+};
+
+// e.g. "Tunnelbana 14 mot Fruängen"
+function lineLabel(meta: LineInfo): string {
+  const parts = [meta.mode ? MODE_LABELS[meta.mode] : undefined, meta.routeShortName].filter(Boolean);
+  const line = parts.join(" ");
+  if (!meta.tripHeadsign) return line;
+  return line ? `${line} mot ${meta.tripHeadsign}` : meta.tripHeadsign;
 }
 
 // --- StopInput Component ---
@@ -72,12 +131,12 @@ function StopInput({
 }: {
   label: string;
   icon: React.ReactNode;
-  value: Stop | null;
-  onSelect: (stop: Stop) => void;
+  value: Station | null;
+  onSelect: (station: Station) => void;
   placeholder: string;
 }) {
-  const [query, setQuery] = useState(value?.stopName ?? "");
-  const [results, setResults] = useState<Stop[]>([]);
+  const [query, setQuery] = useState(value?.name ?? "");
+  const [results, setResults] = useState<Station[]>([]);
   const [open, setOpen] = useState(false);
   const [loading, setLoading] = useState(false);
   const [focused, setFocused] = useState(false);
@@ -85,7 +144,7 @@ function StopInput({
   const inputRef = useRef<HTMLInputElement>(null);
 
   useEffect(() => {
-    if (value) setQuery(value.stopName);
+    if (value) setQuery(value.name);
   }, [value]);
 
   const search = useCallback((q: string) => {
@@ -94,7 +153,7 @@ function StopInput({
     debounceRef.current = setTimeout(async () => {
       setLoading(true);
       try {
-        const data = await fetchStopsByName(q);
+        const data = await fetchStationsByName(q);
         setResults(data ?? []);
         setOpen(true);
       } catch {
@@ -142,10 +201,10 @@ function StopInput({
           background: "#fff", borderRadius: 8, boxShadow: "0 8px 32px rgba(0,0,0,0.14)",
           border: "1px solid #E3E8EF", overflow: "hidden", maxHeight: 260, overflowY: "auto"
         }}>
-          {results.slice(0, 8).map((stop, i) => (
+          {results.slice(0, 8).map((station, i) => (
             <button
-              key={stop.stopId + i}
-              onMouseDown={() => { onSelect(stop); setQuery(stop.stopName); setOpen(false); }}
+              key={station.id + i}
+              onMouseDown={() => { onSelect(station); setQuery(station.name); setOpen(false); }}
               style={{
                 display: "flex", alignItems: "center", gap: 10, width: "100%",
                 padding: "11px 14px", border: "none", background: "none",
@@ -160,8 +219,10 @@ function StopInput({
                 <svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2.5"><circle cx="12" cy="12" r="3" /><path d="M12 2v3M12 19v3M2 12h3M19 12h3" /></svg>
               </span>
               <div>
-                <div style={{ fontSize: 14, fontWeight: 600, color: "#0F1923" }}>{stop.stopName}</div>
-                <div style={{ fontSize: 11, color: "#8A96A3", marginTop: 1 }}>Stop ID: {stop.stopId}</div>
+                <div style={{ fontSize: 14, fontWeight: 600, color: "#0F1923" }}>{station.name}</div>
+                <div style={{ fontSize: 11, color: "#8A96A3", marginTop: 1 }}>
+                  {station.platforms.length === 1 ? "1 platform" : `${station.platforms.length} platforms`}
+                </div>
               </div>
             </button>
           ))}
@@ -172,8 +233,8 @@ function StopInput({
 }
 
 // --- Transfer Indicator ---
-function TransferIndicator({ edge }: { edge: Edge }) {
-  const duration = edge.metadata.arrival - edge.metadata.departure;
+function TransferIndicator({ leg }: { leg: Leg }) {
+  const duration = leg.durationSeconds * 1000;
   return (
     <div style={{ display: "flex", alignItems: "center", gap: 10, padding: "10px 0", color: "#6B7A8D", fontSize: 12 }}>
       <div style={{ display: "flex", flexDirection: "column", alignItems: "center", width: 24, flexShrink: 0 }}>
@@ -187,52 +248,24 @@ function TransferIndicator({ edge }: { edge: Edge }) {
       </div>
       <div style={{ background: "#F8FAFC", border: "1px solid #E3E8EF", borderRadius: 6, padding: "6px 12px", fontSize: 12, color: "#6B7A8D" }}>
         <span style={{ fontWeight: 600 }}>Walk</span>
-        {duration > 0 && <span style={{ marginLeft: 6, color: "#8A96A3" }}>· {travelDuration(edge.metadata.departure, edge.metadata.arrival)}</span>}
-        <span style={{ marginLeft: 6, color: "#8A96A3" }}>· {edge.metadata.sourceStopName} → {edge.metadata.destStopName}</span>
+        {duration > 0 && <span style={{ marginLeft: 6, color: "#8A96A3" }}>· {travelDuration(leg.departure, leg.arrival)}</span>}
+        {!!leg.distance && <span style={{ marginLeft: 6, color: "#8A96A3" }}>· {Math.round(leg.distance)} m</span>}
+        <span style={{ marginLeft: 6, color: "#8A96A3" }}>· {leg.from.stopName} → {leg.to.stopName}</span>
       </div>
     </div>
   );
 }
 
 // --- Route Result ---
-function RouteCard({ edges }: { edges: Edge[] }) {
-  if (!edges || edges.length === 0) return (
+function RouteCard({ journey }: { journey: Journey | null }) {
+  if (!journey || journey.legs.length === 0) return (
     <div style={{ textAlign: "center", padding: "40px 0", color: "#8A96A3", fontStyle: "italic" }}>
       No route found between these stops.
     </div>
   );
 
-  // Separate transit edges from transfer edges for total time calc
-  const transitEdges = edges.filter(e => !isTransferEdge(e));
-  if (transitEdges.length === 0) return null;
-
-  const totalDep = transitEdges[0].metadata.departure;
-  const totalArr = transitEdges[transitEdges.length - 1].metadata.arrival;
-
-  // Group into segments: either a "transit leg" (same tripId) or a "transfer"
-  type Segment =
-    | { type: "leg"; edges: Edge[] }
-    | { type: "transfer"; edge: Edge };
-
-  const segments: Segment[] = [];
-  let currentLeg: Edge[] = [];
-
-  for (const edge of edges) {
-    if (isTransferEdge(edge)) {
-      if (currentLeg.length > 0) {
-        segments.push({ type: "leg", edges: currentLeg });
-        currentLeg = [];
-      }
-      segments.push({ type: "transfer", edge });
-    } else {
-      if (currentLeg.length > 0 && currentLeg[0].metadata.tripId !== edge.metadata.tripId) {
-        segments.push({ type: "leg", edges: currentLeg });
-        currentLeg = [];
-      }
-      currentLeg.push(edge);
-    }
-  }
-  if (currentLeg.length > 0) segments.push({ type: "leg", edges: currentLeg });
+  const totalDep = journey.departure;
+  const totalArr = journey.arrival;
 
   return (
     <div style={{ background: "#fff", borderRadius: 12, boxShadow: "0 2px 16px rgba(0,0,0,0.09)", overflow: "hidden", border: "1px solid #E3E8EF" }}>
@@ -241,28 +274,30 @@ function RouteCard({ edges }: { edges: Edge[] }) {
         <div>
           <div style={{ fontSize: 13, opacity: 0.8, fontWeight: 500, marginBottom: 4 }}>Total journey</div>
           <div style={{ fontSize: 22, fontWeight: 700 }}>
-            {minutesToTime(totalDep)} → {minutesToTime(totalArr)}
+            {formatTime(totalDep)} → {formatTime(totalArr)}
           </div>
         </div>
         <div style={{ textAlign: "right" }}>
           <div style={{ fontSize: 13, opacity: 0.8 }}>Duration</div>
           <div style={{ fontSize: 20, fontWeight: 700 }}>{travelDuration(totalDep, totalArr)}</div>
+          <div style={{ fontSize: 12, opacity: 0.8, marginTop: 2 }}>
+            {journey.transfers} transfer{journey.transfers !== 1 ? "s" : ""} · {Math.round(journey.walkMeters)} m walk
+          </div>
         </div>
       </div>
 
       {/* Segments */}
       <div style={{ padding: "0 24px 20px" }}>
-        {segments.map((seg, si) => {
-          if (seg.type === "transfer") {
-            return <TransferIndicator key={si} edge={seg.edge} />;
+        {journey.legs.map((leg, si) => {
+          if (leg.transferType === 1) {
+            return <TransferIndicator key={si} leg={leg} />;
           }
 
-          const leg = seg.edges;
-          const legDep = leg[0].metadata.departure;
-          const legArr = leg[leg.length - 1].metadata.arrival;
-          const meta = leg[0].metadata;
-          const fromName = meta.sourceStopName;
-          const toName = leg[leg.length - 1].metadata.destStopName;
+          const legDep = leg.departure;
+          const legArr = leg.arrival;
+          const fromName = leg.from.stopName;
+          const toName = leg.to.stopName;
+          const stopCount = leg.intermediateStops.length + 1;
 
           return (
             <div key={si} style={{ paddingTop: si === 0 ? 20 : 0 }}>
@@ -280,7 +315,7 @@ function RouteCard({ edges }: { edges: Edge[] }) {
                   <div style={{ display: "flex", justifyContent: "space-between", alignItems: "flex-start" }}>
                     <div style={{ fontSize: 15, fontWeight: 700, color: "#0F1923" }}>{fromName}</div>
                     <div style={{ fontSize: 16, fontWeight: 700, color: "#006CBF", textAlign: "right", flexShrink: 0, marginLeft: 12 }}>
-                      {minutesToTime(legDep)}
+                      {formatTime(legDep)}
                     </div>
                   </div>
 
@@ -289,10 +324,10 @@ function RouteCard({ edges }: { edges: Edge[] }) {
                     <svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="#006CBF" strokeWidth="2.5">
                       <rect x="2" y="7" width="20" height="13" rx="2" /><path d="M16 7V5a2 2 0 00-2-2h-4a2 2 0 00-2 2v2" /><line x1="12" y1="12" x2="12" y2="16" /><line x1="10" y1="14" x2="14" y2="14" />
                     </svg>
-                    <span style={{ fontSize: 12, color: "#006CBF", fontWeight: 600 }}>{leg.length} stop{leg.length !== 1 ? "s" : ""}</span>
+                    <span style={{ fontSize: 12, color: "#006CBF", fontWeight: 600 }}>{stopCount} stop{stopCount !== 1 ? "s" : ""}</span>
                     <span style={{ fontSize: 11, color: "#5A8ECC" }}>· {travelDuration(legDep, legArr)}</span>
-                    {meta.tripHeadsign && (
-                      <span style={{ fontSize: 11, color: "#5A8ECC" }}>· {meta.tripHeadsign}</span>
+                    {lineLabel(leg) && (
+                      <span style={{ fontSize: 11, color: "#5A8ECC" }}>· {lineLabel(leg)}</span>
                     )}
                   </div>
 
@@ -303,10 +338,10 @@ function RouteCard({ edges }: { edges: Edge[] }) {
                       View intermediate stops
                     </summary>
                     <div style={{ marginTop: 6, paddingLeft: 8, borderLeft: "2px solid #D6E4F3" }}>
-                      {leg.map((e, ei) => (
+                      {leg.intermediateStops.map((s, ei) => (
                         <div key={ei} style={{ display: "flex", justifyContent: "space-between", padding: "3px 0", fontSize: 12, color: "#4A5568" }}>
-                          <span>{e.metadata.destStopName}</span>
-                          <span style={{ color: "#8A96A3" }}>{minutesToTime(e.metadata.arrival)}</span>
+                          <span>{s.stop.stopName}</span>
+                          <span style={{ color: "#8A96A3" }}>{formatTime(s.arrival)}</span>
                         </div>
                       ))}
                     </div>
@@ -315,7 +350,7 @@ function RouteCard({ edges }: { edges: Edge[] }) {
                   {/* Arrival */}
                   <div style={{ display: "flex", justifyContent: "space-between", alignItems: "center" }}>
                     <div style={{ fontSize: 15, fontWeight: 700, color: "#0F1923" }}>{toName}</div>
-                    <div style={{ fontSize: 16, fontWeight: 700, color: "#003F8A" }}>{minutesToTime(legArr)}</div>
+                    <div style={{ fontSize: 16, fontWeight: 700, color: "#003F8A" }}>{formatTime(legArr)}</div>
                   </div>
                 </div>
               </div>
@@ -329,13 +364,13 @@ function RouteCard({ edges }: { edges: Edge[] }) {
 
 // --- Main App ---
 export default function App() {
-  const [from, setFrom] = useState<Stop | null>(null);
-  const [to, setTo] = useState<Stop | null>(null);
+  const [from, setFrom] = useState<Station | null>(null);
+  const [to, setTo] = useState<Station | null>(null);
   const [time, setTime] = useState(() => {
     const now = new Date();
     return now.toTimeString().slice(0, 5);
   });
-  const [route, setRoute] = useState<Edge[] | null>(null);
+  const [route, setRoute] = useState<Journey | null | undefined>(undefined);
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
 
@@ -345,9 +380,9 @@ export default function App() {
     if (!from || !to) return;
     setLoading(true);
     setError(null);
-    setRoute(null);
+    setRoute(undefined);
     try {
-      const data = await fetchRoute(from.stopId, to.stopId, time);
+      const data = await fetchRoute(from.id, to.id, time);
       setRoute(data);
     } catch (e: unknown) {
       setError(e instanceof Error ? e.message : "Could not fetch route");
@@ -492,19 +527,19 @@ export default function App() {
           </div>
         )}
 
-        {route !== null && (
+        {route !== undefined && (
           <div style={{ animation: "fadeUp 0.35s ease" }}>
             <div style={{ display: "flex", justifyContent: "space-between", alignItems: "center", marginBottom: 14 }}>
               <h2 style={{ fontSize: 18, fontWeight: 700, color: "#0F1923", letterSpacing: "-0.02em" }}>
                 Journey result
               </h2>
-              <span style={{ fontSize: 13, color: "#8A96A3" }}>{from?.stopName} → {to?.stopName}</span>
+              <span style={{ fontSize: 13, color: "#8A96A3" }}>{from?.name} → {to?.name}</span>
             </div>
-            <RouteCard edges={route} />
+            <RouteCard journey={route} />
           </div>
         )}
 
-        {!route && !loading && !error && (
+        {route === undefined && !loading && !error && (
           <div style={{ textAlign: "center", paddingTop: 24 }}>
             <div style={{ display: "flex", gap: 16, justifyContent: "center", flexWrap: "wrap" }}>
               {[